@@ -0,0 +1,300 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package dataposter owns the durable queue of sequencer batch transactions that have been signed
+// and sent but not yet confirmed, so that a crash between signing and sending - or a transaction
+// that never gets mined - doesn't silently lose a batch.
+package dataposter
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arbtransaction"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/transactauth"
+)
+
+// dataPosterClient is the subset of ethutils.EthClient DataPoster needs; kept narrow so tests can
+// exercise Send/ReplayUnsent/pollOnce against a fake instead of a real node connection.
+type dataPosterClient interface {
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionByHash(ctx context.Context, txHash ethcommon.Hash) (*types.Transaction, bool, error)
+	TransactionReceipt(ctx context.Context, txHash ethcommon.Hash) (*types.Receipt, error)
+}
+
+// Config controls how aggressively DataPoster polls for receipts and how long it waits before
+// considering a pending transaction stuck.
+type Config struct {
+	// ReceiptPollInterval is how often the background loop checks pending transactions' receipts.
+	ReceiptPollInterval time.Duration
+	// ConfirmationTimeout is how long a transaction can go without a receipt before DataPoster
+	// bumps its fee and rebroadcasts it.
+	ConfirmationTimeout time.Duration
+	// Poster controls fee-bump signing: Signer is used to re-sign a bumped replacement if set,
+	// otherwise auth's own in-memory signer is used.
+	Poster ethbridge.SequencerBatchPosterConfig
+}
+
+var DefaultConfig = Config{
+	ReceiptPollInterval: 15 * time.Second,
+	ConfirmationTimeout: 2 * time.Minute,
+	Poster:              ethbridge.DefaultSequencerBatchPosterConfig,
+}
+
+// pendingEntry is what's persisted in Storage for each in-flight transaction.
+type pendingEntry struct {
+	RawTx     []byte // tx.MarshalBinary()
+	CreatedAt time.Time
+}
+
+// DataPoster owns a durable queue of batch transactions the sequencer has signed and sent but not
+// yet seen confirmed. Unlike a fire-and-forget SendTransaction call, it persists each transaction
+// before sending, watches for its receipt, bumps fees and rebroadcasts on timeout, and replays
+// anything still unsent after a restart, so a crash between signing and sending never silently
+// drops a batch. It implements ethbridge.TxSender, so sequencer batch posters can route through it
+// in place of calling client.SendTransaction directly.
+type DataPoster struct {
+	storage Storage
+	client  dataPosterClient
+	auth    transactauth.TransactAuth
+	config  Config
+	metrics metrics
+
+	mu      sync.Mutex
+	pending map[uint64]*pendingEntry // nonce -> entry
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a DataPoster backed by storage and loads (but does not resend) any entries left over
+// from a previous run; call ReplayUnsent to resend them.
+func New(storage Storage, client ethutils.EthClient, auth transactauth.TransactAuth, config Config) (*DataPoster, error) {
+	p := &DataPoster{
+		storage: storage,
+		client:  client,
+		auth:    auth,
+		config:  config,
+		pending: make(map[uint64]*pendingEntry),
+	}
+	if err := p.loadPending(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *DataPoster) loadPending() error {
+	return p.storage.ForEach(func(nonce uint64, data []byte) error {
+		var e pendingEntry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return errors.WithStack(err)
+		}
+		p.pending[nonce] = &e
+		return nil
+	})
+}
+
+// Send persists tx before broadcasting it, so the entry survives a crash between the two steps,
+// then sends it and starts tracking it for confirmation / fee bumping. It satisfies
+// ethbridge.TxSender.
+func (p *DataPoster) Send(ctx context.Context, tx *types.Transaction) (*arbtransaction.ArbTransaction, error) {
+	if err := p.persist(tx, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := p.client.SendTransaction(ctx, tx); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return arbtransaction.NewArbTransaction(tx), nil
+}
+
+func (p *DataPoster) persist(tx *types.Transaction, createdAt time.Time) error {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	entry := pendingEntry{RawTx: raw, CreatedAt: createdAt}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := p.storage.Put(tx.Nonce(), data); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.pending[tx.Nonce()] = &entry
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *DataPoster) remove(nonce uint64) {
+	p.mu.Lock()
+	delete(p.pending, nonce)
+	p.mu.Unlock()
+	_ = p.storage.Delete(nonce)
+}
+
+func (p *DataPoster) snapshotPending() map[uint64]*pendingEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	snapshot := make(map[uint64]*pendingEntry, len(p.pending))
+	for nonce, e := range p.pending {
+		snapshot[nonce] = e
+	}
+	return snapshot
+}
+
+// ReplayUnsent resends every transaction still in the queue, in case the process crashed after
+// persisting a transaction but before the SendTransaction call reached the node. Call this once
+// after New, before Start.
+func (p *DataPoster) ReplayUnsent(ctx context.Context) error {
+	for _, e := range p.snapshotPending() {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(e.RawTx); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := p.client.SendTransaction(ctx, tx); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// Start launches the background loop that watches pending transactions for receipts and bumps
+// fees on ones that haven't confirmed within config.ConfirmationTimeout. Call Stop to shut it down.
+func (p *DataPoster) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.config.ReceiptPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.pollOnce(ctx)
+			}
+		}
+	}()
+}
+
+// Stop shuts down the background loop started by Start and waits for it to exit.
+func (p *DataPoster) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+}
+
+func (p *DataPoster) pollOnce(ctx context.Context) {
+	for nonce, e := range p.snapshotPending() {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(e.RawTx); err != nil {
+			continue
+		}
+		if confirmed, err := p.isConfirmed(ctx, tx); err == nil && confirmed {
+			p.remove(nonce)
+			continue
+		}
+		if time.Since(e.CreatedAt) < p.config.ConfirmationTimeout {
+			continue
+		}
+		if err := p.bumpAndResend(ctx, tx); err != nil {
+			continue
+		}
+		p.metrics.incFeeBumps()
+	}
+}
+
+func (p *DataPoster) isConfirmed(ctx context.Context, tx *types.Transaction) (bool, error) {
+	_, isPending, err := p.client.TransactionByHash(ctx, tx.Hash())
+	if err != nil {
+		return false, err
+	}
+	if isPending {
+		return false, nil
+	}
+	if _, err := p.client.TransactionReceipt(ctx, tx.Hash()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// bumpAndResend rebuilds stuckTx with a bumped tip/fee cap (per EIP-1559 replacement rules), signs
+// it with whichever signer config.Poster specifies (or auth's own signer otherwise), persists it in
+// place of the old entry, and resends it. The replacement's CreatedAt is reset to now rather than
+// carrying over stuckTx's original timestamp, so pollOnce waits a full ConfirmationTimeout before
+// considering the replacement itself stuck, instead of re-bumping on every following poll tick.
+func (p *DataPoster) bumpAndResend(ctx context.Context, stuckTx *types.Transaction) error {
+	replacement, err := ethbridge.BuildReplacementTx(stuckTx)
+	if err != nil {
+		return err
+	}
+	if signer := p.config.Poster.Signer; signer != nil {
+		replacement, err = signer.SignTx(ctx, replacement)
+	} else {
+		rawAuth := p.auth.GetAuth(ctx)
+		replacement, err = rawAuth.Signer(rawAuth.From, replacement)
+	}
+	if err != nil {
+		return err
+	}
+	if err := p.persist(replacement, time.Now()); err != nil {
+		return err
+	}
+	return p.client.SendTransaction(ctx, replacement)
+}
+
+// QueueDepth returns how many transactions are currently pending confirmation.
+func (p *DataPoster) QueueDepth() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.pending)
+}
+
+// OldestPendingAge returns how long the oldest still-pending transaction has been waiting, or 0 if
+// the queue is empty.
+func (p *DataPoster) OldestPendingAge() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var oldest time.Time
+	for _, e := range p.pending {
+		if oldest.IsZero() || e.CreatedAt.Before(oldest) {
+			oldest = e.CreatedAt
+		}
+	}
+	if oldest.IsZero() {
+		return 0
+	}
+	return time.Since(oldest)
+}
+
+// FeeBumpCount returns how many times the background loop has rebroadcast a pending transaction
+// with a bumped fee.
+func (p *DataPoster) FeeBumpCount() int64 {
+	return p.metrics.FeeBumpCount()
+}
@@ -0,0 +1,347 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataposter
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/transactauth"
+)
+
+func testTx(nonce uint64) *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(2e9),
+		Gas:       21000,
+		Value:     big.NewInt(0),
+	})
+}
+
+// persist and loadPending don't touch p.client, so they can be exercised directly without a fake
+// ethutils.EthClient.
+func newTestDataPoster(t *testing.T, storage Storage) *DataPoster {
+	t.Helper()
+	return &DataPoster{
+		storage: storage,
+		config:  DefaultConfig,
+		pending: make(map[uint64]*pendingEntry),
+	}
+}
+
+func TestDataPosterPersistTracksPending(t *testing.T) {
+	p := newTestDataPoster(t, openTestBoltStorage(t))
+
+	if err := p.persist(testTx(3), time.Now()); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	if p.QueueDepth() != 1 {
+		t.Fatalf("QueueDepth() = %d, want 1", p.QueueDepth())
+	}
+	if _, ok := p.snapshotPending()[3]; !ok {
+		t.Error("persisted transaction not found in the in-memory pending map")
+	}
+}
+
+func TestDataPosterLoadPendingReplaysStorage(t *testing.T) {
+	storage := openTestBoltStorage(t)
+	first := newTestDataPoster(t, storage)
+	if err := first.persist(testTx(1), time.Now()); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	if err := first.persist(testTx(2), time.Now()); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+
+	// Simulate a restart: a fresh DataPoster backed by the same storage should pick up both
+	// still-pending entries via loadPending, the way New does.
+	second := &DataPoster{storage: storage, config: DefaultConfig, pending: make(map[uint64]*pendingEntry)}
+	if err := second.loadPending(); err != nil {
+		t.Fatalf("loadPending: %v", err)
+	}
+	if second.QueueDepth() != 2 {
+		t.Fatalf("QueueDepth() after loadPending = %d, want 2", second.QueueDepth())
+	}
+}
+
+func TestDataPosterRemoveDropsFromMemoryAndStorage(t *testing.T) {
+	storage := openTestBoltStorage(t)
+	p := newTestDataPoster(t, storage)
+	if err := p.persist(testTx(4), time.Now()); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+
+	p.remove(4)
+
+	if p.QueueDepth() != 0 {
+		t.Fatalf("QueueDepth() after remove = %d, want 0", p.QueueDepth())
+	}
+	reloaded := &DataPoster{storage: storage, config: DefaultConfig, pending: make(map[uint64]*pendingEntry)}
+	if err := reloaded.loadPending(); err != nil {
+		t.Fatalf("loadPending: %v", err)
+	}
+	if reloaded.QueueDepth() != 0 {
+		t.Fatalf("QueueDepth() after reloading post-remove storage = %d, want 0", reloaded.QueueDepth())
+	}
+}
+
+func TestDataPosterPersistSameNonceReplacesEntry(t *testing.T) {
+	// bumpAndResend re-persists a replacement transaction under the same nonce as the stuck one; the
+	// queue should end up with the bumped transaction in place of the original, not both.
+	p := newTestDataPoster(t, openTestBoltStorage(t))
+	firstCreatedAt := time.Now().Add(-time.Hour)
+
+	if err := p.persist(testTx(7), firstCreatedAt); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	replacement := types.NewTx(&types.DynamicFeeTx{
+		Nonce:     7,
+		GasTipCap: big.NewInt(2e9),
+		GasFeeCap: big.NewInt(4e9),
+		Gas:       21000,
+		Value:     big.NewInt(0),
+	})
+	if err := p.persist(replacement, firstCreatedAt); err != nil {
+		t.Fatalf("persist replacement: %v", err)
+	}
+
+	if p.QueueDepth() != 1 {
+		t.Fatalf("QueueDepth() = %d, want 1 (replacement should overwrite, not add)", p.QueueDepth())
+	}
+	entry := p.snapshotPending()[7]
+	gotTx := new(types.Transaction)
+	if err := gotTx.UnmarshalBinary(entry.RawTx); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if gotTx.GasFeeCap().Cmp(replacement.GasFeeCap()) != 0 {
+		t.Errorf("stored entry's GasFeeCap = %s, want the replacement's %s", gotTx.GasFeeCap(), replacement.GasFeeCap())
+	}
+}
+
+func TestDataPosterOldestPendingAge(t *testing.T) {
+	p := newTestDataPoster(t, openTestBoltStorage(t))
+	if age := p.OldestPendingAge(); age != 0 {
+		t.Fatalf("OldestPendingAge() on an empty queue = %v, want 0", age)
+	}
+
+	older := time.Now().Add(-time.Hour)
+	newer := time.Now().Add(-time.Minute)
+	if err := p.persist(testTx(1), newer); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	if err := p.persist(testTx(2), older); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+
+	age := p.OldestPendingAge()
+	if age < 55*time.Minute || age > 65*time.Minute {
+		t.Errorf("OldestPendingAge() = %v, want roughly 1h (the age of the older entry)", age)
+	}
+}
+
+// fakeDataPosterClient is a minimal dataPosterClient: SendTransaction records what was sent, and a
+// transaction only reads back as confirmed once the test explicitly calls confirm on its hash.
+type fakeDataPosterClient struct {
+	mu        sync.Mutex
+	sent      []*types.Transaction
+	confirmed map[ethcommon.Hash]bool
+}
+
+func newFakeDataPosterClient() *fakeDataPosterClient {
+	return &fakeDataPosterClient{confirmed: make(map[ethcommon.Hash]bool)}
+}
+
+func (c *fakeDataPosterClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, tx)
+	return nil
+}
+
+func (c *fakeDataPosterClient) TransactionByHash(ctx context.Context, txHash ethcommon.Hash) (*types.Transaction, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return nil, !c.confirmed[txHash], nil
+}
+
+func (c *fakeDataPosterClient) TransactionReceipt(ctx context.Context, txHash ethcommon.Hash) (*types.Receipt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.confirmed[txHash] {
+		return &types.Receipt{}, nil
+	}
+	return nil, errors.New("receipt not found")
+}
+
+func (c *fakeDataPosterClient) confirm(hash ethcommon.Hash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.confirmed[hash] = true
+}
+
+// fakeTxSigner is a no-op transactauth.TxSigner, so bumpAndResend's signing step doesn't need a real
+// key; it lets these tests avoid going through p.auth entirely.
+type fakeTxSigner struct{}
+
+func (fakeTxSigner) SignTx(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	return tx, nil
+}
+
+func newTestDataPosterWithClient(t *testing.T, storage Storage, client dataPosterClient) *DataPoster {
+	t.Helper()
+	config := DefaultConfig
+	config.Poster.Signer = fakeTxSigner{}
+	return &DataPoster{
+		storage: storage,
+		client:  client,
+		config:  config,
+		pending: make(map[uint64]*pendingEntry),
+	}
+}
+
+func TestDataPosterSendPersistsAndBroadcasts(t *testing.T) {
+	client := newFakeDataPosterClient()
+	p := newTestDataPosterWithClient(t, openTestBoltStorage(t), client)
+
+	if _, err := p.Send(context.Background(), testTx(1)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if p.QueueDepth() != 1 {
+		t.Fatalf("QueueDepth() = %d, want 1", p.QueueDepth())
+	}
+	if len(client.sent) != 1 {
+		t.Fatalf("client saw %d sent transactions, want 1", len(client.sent))
+	}
+}
+
+func TestDataPosterReplayUnsentResendsEveryPendingEntry(t *testing.T) {
+	storage := openTestBoltStorage(t)
+	client := newFakeDataPosterClient()
+	p := newTestDataPosterWithClient(t, storage, client)
+
+	// Simulate a crash between persist and send: entries are in storage but were never sent.
+	if err := p.persist(testTx(1), time.Now()); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	if err := p.persist(testTx(2), time.Now()); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+
+	if err := p.ReplayUnsent(context.Background()); err != nil {
+		t.Fatalf("ReplayUnsent: %v", err)
+	}
+	if len(client.sent) != 2 {
+		t.Fatalf("client saw %d resent transactions, want 2", len(client.sent))
+	}
+}
+
+func TestDataPosterPollOnceRemovesConfirmedTx(t *testing.T) {
+	client := newFakeDataPosterClient()
+	p := newTestDataPosterWithClient(t, openTestBoltStorage(t), client)
+
+	tx := testTx(1)
+	if err := p.persist(tx, time.Now()); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+	client.confirm(tx.Hash())
+
+	p.pollOnce(context.Background())
+
+	if p.QueueDepth() != 0 {
+		t.Fatalf("QueueDepth() after confirming = %d, want 0", p.QueueDepth())
+	}
+}
+
+func TestDataPosterPollOnceBumpsTxPastConfirmationTimeout(t *testing.T) {
+	client := newFakeDataPosterClient()
+	p := newTestDataPosterWithClient(t, openTestBoltStorage(t), client)
+
+	tx := testTx(1)
+	createdAt := time.Now().Add(-p.config.ConfirmationTimeout - time.Minute)
+	if err := p.persist(tx, createdAt); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+
+	p.pollOnce(context.Background())
+
+	if p.FeeBumpCount() != 1 {
+		t.Fatalf("FeeBumpCount() = %d, want 1", p.FeeBumpCount())
+	}
+	entry := p.snapshotPending()[1]
+	if entry == nil {
+		t.Fatal("nonce 1 missing from pending after a bump, want the replacement still tracked under the same nonce")
+	}
+	// bumpAndResend must reset CreatedAt to now rather than carrying over the stuck tx's original
+	// timestamp, or pollOnce will treat the fresh replacement as already stuck too.
+	if time.Since(entry.CreatedAt) > time.Minute {
+		t.Errorf("replacement entry's CreatedAt = %v, want close to now", entry.CreatedAt)
+	}
+}
+
+func TestDataPosterPollOnceDoesNotRebumpImmediatelyAfterABump(t *testing.T) {
+	client := newFakeDataPosterClient()
+	p := newTestDataPosterWithClient(t, openTestBoltStorage(t), client)
+
+	tx := testTx(1)
+	createdAt := time.Now().Add(-p.config.ConfirmationTimeout - time.Minute)
+	if err := p.persist(tx, createdAt); err != nil {
+		t.Fatalf("persist: %v", err)
+	}
+
+	p.pollOnce(context.Background())
+	if p.FeeBumpCount() != 1 {
+		t.Fatalf("FeeBumpCount() after first poll = %d, want 1", p.FeeBumpCount())
+	}
+
+	// A second poll tick right away shouldn't bump again: the replacement's CreatedAt was just reset,
+	// so it hasn't had time to exceed ConfirmationTimeout yet.
+	p.pollOnce(context.Background())
+	if p.FeeBumpCount() != 1 {
+		t.Fatalf("FeeBumpCount() after second, immediate poll = %d, want still 1 (no re-bump within ConfirmationTimeout)", p.FeeBumpCount())
+	}
+}
+
+func TestDataPosterBumpAndResendPreservesNonceAndBumpsFees(t *testing.T) {
+	client := newFakeDataPosterClient()
+	p := newTestDataPosterWithClient(t, openTestBoltStorage(t), client)
+
+	stuckTx := testTx(3)
+	if err := p.bumpAndResend(context.Background(), stuckTx); err != nil {
+		t.Fatalf("bumpAndResend: %v", err)
+	}
+
+	if len(client.sent) != 1 {
+		t.Fatalf("client saw %d sent transactions, want 1", len(client.sent))
+	}
+	replacement := client.sent[0]
+	if replacement.Nonce() != stuckTx.Nonce() {
+		t.Errorf("replacement nonce = %d, want %d (same as the stuck tx)", replacement.Nonce(), stuckTx.Nonce())
+	}
+	if replacement.GasFeeCap().Cmp(stuckTx.GasFeeCap()) <= 0 {
+		t.Errorf("replacement GasFeeCap = %s, want strictly greater than stuck tx's %s", replacement.GasFeeCap(), stuckTx.GasFeeCap())
+	}
+}
+
+var _ transactauth.TxSigner = fakeTxSigner{}
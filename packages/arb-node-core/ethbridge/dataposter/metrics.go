@@ -0,0 +1,33 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataposter
+
+import "sync/atomic"
+
+// metrics holds the counters DataPoster exposes. All fields are accessed atomically so the
+// background loop can update them without taking the same lock callers use to read them.
+type metrics struct {
+	feeBumps int64
+}
+
+func (m *metrics) incFeeBumps() {
+	atomic.AddInt64(&m.feeBumps, 1)
+}
+
+func (m *metrics) FeeBumpCount() int64 {
+	return atomic.LoadInt64(&m.feeBumps)
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataposter
+
+import (
+	"encoding/binary"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/pkg/errors"
+)
+
+// Storage persists pending entries keyed by nonce, so a crash between signing and sending a batch
+// transaction doesn't lose it: on restart the caller can re-read everything still in the queue and
+// either rebroadcast it or confirm it already landed.
+type Storage interface {
+	Put(nonce uint64, data []byte) error
+	Delete(nonce uint64) error
+	ForEach(f func(nonce uint64, data []byte) error) error
+	Close() error
+}
+
+var queueBucket = []byte("dataposter-queue")
+
+// BoltStorage is a Storage backed by a single bolt (go.etcd.io/bbolt) database file.
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+// OpenBoltStorage opens (creating if necessary) a bolt-backed queue at path.
+func OpenBoltStorage(path string) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(queueBucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, errors.WithStack(err)
+	}
+	return &BoltStorage{db: db}, nil
+}
+
+func nonceKey(nonce uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, nonce)
+	return key
+}
+
+func (s *BoltStorage) Put(nonce uint64, data []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(queueBucket).Put(nonceKey(nonce), data)
+	})
+}
+
+func (s *BoltStorage) Delete(nonce uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(queueBucket).Delete(nonceKey(nonce))
+	})
+}
+
+func (s *BoltStorage) ForEach(f func(nonce uint64, data []byte) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(queueBucket).ForEach(func(k, v []byte) error {
+			return f(binary.BigEndian.Uint64(k), append([]byte{}, v...))
+		})
+	})
+}
+
+func (s *BoltStorage) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataposter
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func openTestBoltStorage(t *testing.T) *BoltStorage {
+	t.Helper()
+	storage, err := OpenBoltStorage(filepath.Join(t.TempDir(), "dataposter.db"))
+	if err != nil {
+		t.Fatalf("OpenBoltStorage: %v", err)
+	}
+	t.Cleanup(func() { _ = storage.Close() })
+	return storage
+}
+
+func TestBoltStoragePutGetDelete(t *testing.T) {
+	storage := openTestBoltStorage(t)
+
+	if err := storage.Put(1, []byte("one")); err != nil {
+		t.Fatalf("Put(1): %v", err)
+	}
+	if err := storage.Put(2, []byte("two")); err != nil {
+		t.Fatalf("Put(2): %v", err)
+	}
+
+	seen := map[uint64]string{}
+	if err := storage.ForEach(func(nonce uint64, data []byte) error {
+		seen[nonce] = string(data)
+		return nil
+	}); err != nil {
+		t.Fatalf("ForEach: %v", err)
+	}
+	if seen[1] != "one" || seen[2] != "two" {
+		t.Fatalf("ForEach returned %v, want {1: one, 2: two}", seen)
+	}
+
+	if err := storage.Delete(1); err != nil {
+		t.Fatalf("Delete(1): %v", err)
+	}
+	seen = map[uint64]string{}
+	if err := storage.ForEach(func(nonce uint64, data []byte) error {
+		seen[nonce] = string(data)
+		return nil
+	}); err != nil {
+		t.Fatalf("ForEach: %v", err)
+	}
+	if _, ok := seen[1]; ok {
+		t.Error("nonce 1 still present after Delete")
+	}
+	if seen[2] != "two" {
+		t.Fatalf("ForEach after delete returned %v, want {2: two}", seen)
+	}
+}
+
+func TestBoltStoragePutOverwritesSameNonce(t *testing.T) {
+	storage := openTestBoltStorage(t)
+
+	if err := storage.Put(5, []byte("first")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := storage.Put(5, []byte("bumped")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	count := 0
+	var last string
+	if err := storage.ForEach(func(nonce uint64, data []byte) error {
+		count++
+		last = string(data)
+		return nil
+	}); err != nil {
+		t.Fatalf("ForEach: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("got %d entries for nonce 5, want 1 (Put should overwrite, not append)", count)
+	}
+	if last != "bumped" {
+		t.Fatalf("entry for nonce 5 = %q, want %q", last, "bumped")
+	}
+}
+
+func TestBoltStoragePersistsAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dataposter.db")
+
+	storage, err := OpenBoltStorage(path)
+	if err != nil {
+		t.Fatalf("OpenBoltStorage: %v", err)
+	}
+	if err := storage.Put(9, []byte("surviving a restart")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := OpenBoltStorage(path)
+	if err != nil {
+		t.Fatalf("reopen OpenBoltStorage: %v", err)
+	}
+	defer reopened.Close()
+
+	found := false
+	if err := reopened.ForEach(func(nonce uint64, data []byte) error {
+		if nonce == 9 {
+			found = true
+			if string(data) != "surviving a restart" {
+				t.Errorf("data for nonce 9 = %q, want %q", data, "surviving a restart")
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("ForEach: %v", err)
+	}
+	if !found {
+		t.Error("entry persisted before Close was not found after reopening the database")
+	}
+}
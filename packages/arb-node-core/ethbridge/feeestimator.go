@@ -0,0 +1,195 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arbtransaction"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/transactauth"
+)
+
+// FeeEstimator picks a gas tip cap for a sequencer batch transaction given the parent header; the
+// fee cap is then derived from that tip by computeGasFeeCapAndLimit. Implementations are free to hard-code a value (as AddSequencerL2BatchFromOriginCustomNonce
+// used to) or derive one from recent network activity.
+type FeeEstimator interface {
+	EstimateFees(ctx context.Context, latestHeader *types.Header) (gasTipCap *big.Int, err error)
+}
+
+// FixedFeeEstimator reproduces the fee choice AddSequencerL2BatchFromOriginCustomNonce always made:
+// a flat 1.5 gwei tip, with computeGasFeeCapAndLimit deriving the fee cap from the base fee as before.
+type FixedFeeEstimator struct {
+	GasTipCap *big.Int
+}
+
+var DefaultFixedFeeEstimator = &FixedFeeEstimator{GasTipCap: big.NewInt(15e8)} // 1.5 gwei
+
+func (e *FixedFeeEstimator) EstimateFees(ctx context.Context, latestHeader *types.Header) (*big.Int, error) {
+	return e.GasTipCap, nil
+}
+
+// feeHistoryClient is the subset of ethclient.Client's FeeHistory this package needs; it's
+// satisfied by ethutils.EthClient in practice.
+type feeHistoryClient interface {
+	FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error)
+}
+
+// FeeHistoryEstimatorConfig tunes how FeeHistoryEstimator turns eth_feeHistory data into a tip cap.
+type FeeHistoryEstimatorConfig struct {
+	// WindowBlocks is how many trailing blocks of fee history to average over.
+	WindowBlocks uint64
+	// RewardPercentile selects which reward percentile bucket to read from eth_feeHistory; a lower
+	// percentile targets a longer inclusion delay, a higher one targets faster inclusion.
+	RewardPercentile float64
+	// MinGasTipCap floors the computed tip so we never fall below what most clients will relay.
+	MinGasTipCap *big.Int
+}
+
+// DefaultFeeHistoryEstimatorConfig targets inclusion within the next couple of blocks by averaging
+// each of the last 20 blocks' median (50th-percentile) reward.
+var DefaultFeeHistoryEstimatorConfig = FeeHistoryEstimatorConfig{
+	WindowBlocks:     20,
+	RewardPercentile: 50,
+	MinGasTipCap:     big.NewInt(15e8), // 1.5 gwei
+}
+
+// FeeHistoryEstimator derives a gas tip cap from eth_feeHistory reward data instead of hard-coding
+// one, so the tip adapts to current network congestion.
+type FeeHistoryEstimator struct {
+	client feeHistoryClient
+	config FeeHistoryEstimatorConfig
+}
+
+func NewFeeHistoryEstimator(client feeHistoryClient, config FeeHistoryEstimatorConfig) *FeeHistoryEstimator {
+	return &FeeHistoryEstimator{client: client, config: config}
+}
+
+func (e *FeeHistoryEstimator) EstimateFees(ctx context.Context, latestHeader *types.Header) (*big.Int, error) {
+	history, err := e.client.FeeHistory(ctx, e.config.WindowBlocks, latestHeader.Number, []float64{e.config.RewardPercentile})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(history.Reward) == 0 {
+		return e.config.MinGasTipCap, nil
+	}
+	sum := big.NewInt(0)
+	count := 0
+	for _, blockRewards := range history.Reward {
+		if len(blockRewards) == 0 {
+			continue
+		}
+		sum.Add(sum, blockRewards[0])
+		count++
+	}
+	if count == 0 {
+		return e.config.MinGasTipCap, nil
+	}
+	gasTipCap := sum.Div(sum, big.NewInt(int64(count)))
+	if gasTipCap.Cmp(e.config.MinGasTipCap) < 0 {
+		return e.config.MinGasTipCap, nil
+	}
+	return gasTipCap, nil
+}
+
+// bumpByMinPercent scales v up by at least pct percent, rounding up, and by at least 1 wei so
+// repeated replacements always strictly increase even when v is tiny.
+func bumpByMinPercent(v *big.Int, pct int64) *big.Int {
+	bumped := new(big.Int).Mul(v, big.NewInt(100+pct))
+	bumped.Add(bumped, big.NewInt(99))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(v) <= 0 {
+		bumped = new(big.Int).Add(v, big.NewInt(1))
+	}
+	return bumped
+}
+
+// replacementTipAndFeeCapMinPercent is the minimum bump go-ethereum's tx pool requires to accept a
+// replacement for a transaction with the same nonce.
+const replacementTipAndFeeCapMinPercent = 10
+
+// BuildReplacementTx constructs the *unsigned* transaction that would replace stuckTx: the same
+// nonce, To, value, and data, but a gas tip cap and fee cap each bumped by at least
+// replacementTipAndFeeCapMinPercent, as go-ethereum's tx pool requires to accept a same-nonce
+// replacement. When stuckTx is a blob transaction, the replacement is itself a BlobTx carrying the
+// same BlobHashes and Sidecar with BlobFeeCap bumped the same way, since a same-nonce replacement
+// for a blob tx must still carry its blobs or the sequencer inbox won't see the batch data. It's
+// split out from ReplaceStuckTx so callers that manage their own signing and sending (like
+// dataposter.DataPoster) can reuse the bump math without going through this package's own
+// sign-and-send path.
+func BuildReplacementTx(stuckTx *types.Transaction) (*types.Transaction, error) {
+	if stuckTx.Type() != types.DynamicFeeTxType && stuckTx.Type() != types.BlobTxType {
+		return nil, errors.New("can only replace a dynamic-fee or blob transaction")
+	}
+	gasTipCap := bumpByMinPercent(stuckTx.GasTipCap(), replacementTipAndFeeCapMinPercent)
+	gasFeeCap := bumpByMinPercent(stuckTx.GasFeeCap(), replacementTipAndFeeCapMinPercent)
+	if gasCharge := new(big.Int).Mul(gasFeeCap, new(big.Int).SetUint64(stuckTx.Gas())); gasCharge.Cmp(maxGasChargeWei) > 0 {
+		return nil, errors.Errorf("bumped gas charge %s exceeds max %s", gasCharge, maxGasChargeWei)
+	}
+	if stuckTx.Type() == types.BlobTxType {
+		to := stuckTx.To()
+		if to == nil {
+			return nil, errors.New("blob transaction missing to address")
+		}
+		blobFeeCap := bumpByMinPercent(stuckTx.BlobGasFeeCap(), replacementTipAndFeeCapMinPercent)
+		return types.NewTx(&types.BlobTx{
+			ChainID:    uint256.MustFromBig(stuckTx.ChainId()),
+			Nonce:      stuckTx.Nonce(),
+			GasTipCap:  uint256.MustFromBig(gasTipCap),
+			GasFeeCap:  uint256.MustFromBig(gasFeeCap),
+			Gas:        stuckTx.Gas(),
+			To:         *to,
+			Value:      uint256.MustFromBig(stuckTx.Value()),
+			Data:       stuckTx.Data(),
+			BlobFeeCap: uint256.MustFromBig(blobFeeCap),
+			BlobHashes: stuckTx.BlobHashes(),
+			Sidecar:    stuckTx.BlobTxSidecar(),
+		}), nil
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		Nonce:     stuckTx.Nonce(),
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+		Gas:       stuckTx.Gas(),
+		To:        stuckTx.To(),
+		Value:     stuckTx.Value(),
+		Data:      stuckTx.Data(),
+	}), nil
+}
+
+// ReplaceStuckTx re-signs stuckTx with the same nonce but a gas tip cap and fee cap each bumped by
+// at least replacementTipAndFeeCapMinPercent, then rebroadcasts it. Use this when a previously sent
+// sequencer batch transaction hasn't confirmed after a reasonable wait, instead of only bumping the
+// nonce and abandoning it as AddSequencerL2BatchFromOriginCustomNonce's callers historically did.
+func ReplaceStuckTx(ctx context.Context, client ethutils.EthClient, auth transactauth.TransactAuth, stuckTx *types.Transaction, cfg SequencerBatchPosterConfig) (*arbtransaction.ArbTransaction, error) {
+	replacement, err := BuildReplacementTx(stuckTx)
+	if err != nil {
+		return nil, err
+	}
+	rawAuth := auth.GetAuth(ctx)
+	replacement, err = cfg.signTx(ctx, rawAuth, replacement)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.send(ctx, client, replacement)
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/holiman/uint256"
+)
+
+type fakeFeeHistoryClient struct {
+	history *ethereum.FeeHistory
+	err     error
+}
+
+func (f *fakeFeeHistoryClient) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	return f.history, f.err
+}
+
+func TestFeeHistoryEstimatorAverages(t *testing.T) {
+	client := &fakeFeeHistoryClient{
+		history: &ethereum.FeeHistory{
+			Reward: [][]*big.Int{
+				{big.NewInt(10e9)},
+				{big.NewInt(20e9)},
+				{big.NewInt(30e9)},
+			},
+		},
+	}
+	estimator := NewFeeHistoryEstimator(client, DefaultFeeHistoryEstimatorConfig)
+
+	gasTipCap, err := estimator.EstimateFees(context.Background(), &types.Header{})
+	if err != nil {
+		t.Fatalf("EstimateFees: %v", err)
+	}
+	want := big.NewInt(20e9) // (10+20+30)/3 gwei
+	if gasTipCap.Cmp(want) != 0 {
+		t.Errorf("gasTipCap = %s, want %s", gasTipCap, want)
+	}
+}
+
+func TestFeeHistoryEstimatorSkipsEmptyBlocks(t *testing.T) {
+	client := &fakeFeeHistoryClient{
+		history: &ethereum.FeeHistory{
+			Reward: [][]*big.Int{
+				{big.NewInt(10e9)},
+				{}, // a block with no matching reward bucket shouldn't count toward the average
+				{big.NewInt(30e9)},
+			},
+		},
+	}
+	estimator := NewFeeHistoryEstimator(client, DefaultFeeHistoryEstimatorConfig)
+
+	gasTipCap, err := estimator.EstimateFees(context.Background(), &types.Header{})
+	if err != nil {
+		t.Fatalf("EstimateFees: %v", err)
+	}
+	want := big.NewInt(20e9) // (10+30)/2 gwei, ignoring the empty block
+	if gasTipCap.Cmp(want) != 0 {
+		t.Errorf("gasTipCap = %s, want %s", gasTipCap, want)
+	}
+}
+
+func TestFeeHistoryEstimatorFloorsAtMin(t *testing.T) {
+	config := FeeHistoryEstimatorConfig{
+		WindowBlocks:     DefaultFeeHistoryEstimatorConfig.WindowBlocks,
+		RewardPercentile: DefaultFeeHistoryEstimatorConfig.RewardPercentile,
+		MinGasTipCap:     big.NewInt(5e9),
+	}
+	client := &fakeFeeHistoryClient{
+		history: &ethereum.FeeHistory{
+			Reward: [][]*big.Int{{big.NewInt(1e9)}},
+		},
+	}
+	estimator := NewFeeHistoryEstimator(client, config)
+
+	gasTipCap, err := estimator.EstimateFees(context.Background(), &types.Header{})
+	if err != nil {
+		t.Fatalf("EstimateFees: %v", err)
+	}
+	if gasTipCap.Cmp(config.MinGasTipCap) != 0 {
+		t.Errorf("gasTipCap = %s, want the floor %s", gasTipCap, config.MinGasTipCap)
+	}
+}
+
+func TestFeeHistoryEstimatorNoRewardData(t *testing.T) {
+	client := &fakeFeeHistoryClient{history: &ethereum.FeeHistory{}}
+	estimator := NewFeeHistoryEstimator(client, DefaultFeeHistoryEstimatorConfig)
+
+	gasTipCap, err := estimator.EstimateFees(context.Background(), &types.Header{})
+	if err != nil {
+		t.Fatalf("EstimateFees: %v", err)
+	}
+	if gasTipCap.Cmp(DefaultFeeHistoryEstimatorConfig.MinGasTipCap) != 0 {
+		t.Errorf("gasTipCap = %s, want the floor %s", gasTipCap, DefaultFeeHistoryEstimatorConfig.MinGasTipCap)
+	}
+}
+
+func TestBumpByMinPercent(t *testing.T) {
+	tests := []struct {
+		name string
+		v    *big.Int
+		pct  int64
+		want *big.Int
+	}{
+		{"typical value", big.NewInt(100), 10, big.NewInt(110)},
+		{"rounds up at least 1 wei when the percentage bump rounds to zero", big.NewInt(1), 10, big.NewInt(2)},
+		{"zero value still advances by 1 wei", big.NewInt(0), 10, big.NewInt(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bumpByMinPercent(tt.v, tt.pct)
+			if got.Cmp(tt.want) != 0 {
+				t.Errorf("bumpByMinPercent(%s, %d) = %s, want %s", tt.v, tt.pct, got, tt.want)
+			}
+			if got.Cmp(tt.v) <= 0 {
+				t.Errorf("bumpByMinPercent(%s, %d) = %s did not strictly increase", tt.v, tt.pct, got)
+			}
+		})
+	}
+}
+
+func TestBuildReplacementTxRejectsLegacyTx(t *testing.T) {
+	legacyTx := types.NewTx(&types.LegacyTx{Nonce: 0, Gas: 21000, GasPrice: big.NewInt(1e9)})
+	if _, err := BuildReplacementTx(legacyTx); err == nil {
+		t.Fatal("expected an error replacing a legacy transaction, got nil")
+	}
+}
+
+func TestBuildReplacementTxBumpsDynamicFeeTx(t *testing.T) {
+	stuckTx := types.NewTx(&types.DynamicFeeTx{
+		Nonce:     5,
+		GasTipCap: big.NewInt(1000),
+		GasFeeCap: big.NewInt(2000),
+		Gas:       21000,
+		Value:     big.NewInt(0),
+	})
+
+	replacement, err := BuildReplacementTx(stuckTx)
+	if err != nil {
+		t.Fatalf("BuildReplacementTx: %v", err)
+	}
+	if replacement.Nonce() != stuckTx.Nonce() {
+		t.Errorf("nonce = %d, want %d", replacement.Nonce(), stuckTx.Nonce())
+	}
+	if replacement.GasTipCap().Cmp(stuckTx.GasTipCap()) <= 0 {
+		t.Errorf("GasTipCap %s did not increase from %s", replacement.GasTipCap(), stuckTx.GasTipCap())
+	}
+	if replacement.GasFeeCap().Cmp(stuckTx.GasFeeCap()) <= 0 {
+		t.Errorf("GasFeeCap %s did not increase from %s", replacement.GasFeeCap(), stuckTx.GasFeeCap())
+	}
+}
+
+func TestBuildReplacementTxPreservesBlobFields(t *testing.T) {
+	to := ethcommon.HexToAddress("0x1111111111111111111111111111111111111111")
+	versionedHash := ethcommon.HexToHash("0x0122222222222222222222222222222222222222222222222222222222222222")
+	sidecar := &types.BlobTxSidecar{Blobs: []kzg4844.Blob{{1, 2, 3}}}
+	stuckTx := types.NewTx(&types.BlobTx{
+		ChainID:    uint256.NewInt(42161),
+		Nonce:      7,
+		GasTipCap:  uint256.NewInt(1000),
+		GasFeeCap:  uint256.NewInt(2000),
+		Gas:        21000,
+		To:         to,
+		Value:      uint256.NewInt(0),
+		BlobFeeCap: uint256.NewInt(500),
+		BlobHashes: []ethcommon.Hash{versionedHash},
+		Sidecar:    sidecar,
+	})
+
+	replacement, err := BuildReplacementTx(stuckTx)
+	if err != nil {
+		t.Fatalf("BuildReplacementTx: %v", err)
+	}
+	if replacement.Type() != types.BlobTxType {
+		t.Fatalf("replacement type = %d, want a BlobTx (%d)", replacement.Type(), types.BlobTxType)
+	}
+	if replacement.BlobGasFeeCap().Cmp(stuckTx.BlobGasFeeCap()) <= 0 {
+		t.Errorf("BlobFeeCap %s did not increase from %s", replacement.BlobGasFeeCap(), stuckTx.BlobGasFeeCap())
+	}
+	if len(replacement.BlobHashes()) != 1 || replacement.BlobHashes()[0] != versionedHash {
+		t.Errorf("BlobHashes = %v, want %v preserved from the stuck transaction", replacement.BlobHashes(), []ethcommon.Hash{versionedHash})
+	}
+	if replacement.BlobTxSidecar() == nil || len(replacement.BlobTxSidecar().Blobs) != 1 {
+		t.Error("replacement transaction is missing the stuck transaction's blob sidecar")
+	}
+}
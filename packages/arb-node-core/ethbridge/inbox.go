@@ -51,24 +51,35 @@ type StandardInboxWatcher struct {
 	con     *ethbridgecontracts.Inbox
 	address ethcommon.Address
 	client  ethutils.EthClient
+	// logFetcher, if set, is used to resolve fillMessageDetails' logs instead of a single unsharded
+	// FilterLogs call, so large or rate-limited block ranges don't stall the whole scan. See
+	// NewStandardInboxWatcherWithLogFetcher.
+	logFetcher *LogFetcher
 }
 
 func NewStandardInboxWatcher(address ethcommon.Address, client ethutils.EthClient) (*StandardInboxWatcher, error) {
+	return NewStandardInboxWatcherWithLogFetcher(address, client, nil)
+}
+
+// NewStandardInboxWatcherWithLogFetcher is like NewStandardInboxWatcher, but lets the caller supply
+// a LogFetcher so fillMessageDetails shards large block ranges, batches transaction lookups, and
+// optionally caches results on disk instead of issuing one FilterLogs call per scan.
+func NewStandardInboxWatcherWithLogFetcher(address ethcommon.Address, client ethutils.EthClient, logFetcher *LogFetcher) (*StandardInboxWatcher, error) {
 	con, err := ethbridgecontracts.NewInbox(address, client)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 	return &StandardInboxWatcher{
-		con:     con,
-		address: address,
-		client:  client,
+		con:        con,
+		address:    address,
+		client:     client,
+		logFetcher: logFetcher,
 	}, nil
 }
 
 func (r *StandardInboxWatcher) fillMessageDetails(
 	ctx context.Context,
 	messageNums []*big.Int,
-	txData map[string]*types.Transaction,
 	messages map[string][]byte,
 	minBlockNum, maxBlockNum uint64,
 ) error {
@@ -87,10 +98,23 @@ func (r *StandardInboxWatcher) fillMessageDetails(
 		Addresses: []ethcommon.Address{r.address},
 		Topics:    [][]ethcommon.Hash{{inboxMessageDeliveredID, inboxMessageFromOriginID}, msgQuery},
 	}
-	logs, err := r.client.FilterLogs(ctx, query)
+
+	logFetcher := r.logFetcher
+	if logFetcher == nil {
+		// No LogFetcher was configured; fall back to a bare-bones one (no sharding concurrency
+		// beyond a single shard, no cache) so fillMessageDetails still only has one code path for
+		// resolving origin messages' transactions.
+		var err error
+		logFetcher, err = NewLogFetcher(r.client, LogFetcherConfig{MaxConcurrentShards: 1, MinShardBlocks: DefaultLogFetcherConfig.MinShardBlocks})
+		if err != nil {
+			return err
+		}
+	}
+	logs, txData, err := logFetcher.FetchMessageLogs(ctx, query, messageNums, inboxMessageFromOriginID)
 	if err != nil {
-		return errors.WithStack(err)
+		return err
 	}
+
 	for _, ethLog := range logs {
 		msgNum, msg, err := r.parseMessage(txData, ethLog)
 		if err != nil {
@@ -113,9 +137,13 @@ func (r *StandardInboxWatcher) parseMessage(txData map[string]*types.Transaction
 		if err != nil {
 			return nil, nil, errors.WithStack(err)
 		}
+		// fillMessageDetails always resolves a transaction for every origin log via LogFetcher
+		// before calling parseMessage, so a miss here means the fetcher's guarantee was violated.
+		// Kept intentionally as a safety net rather than removed outright: an invariant violation
+		// here should fail loudly instead of panicking on a nil tx.
 		tx, ok := txData[string(parsedLog.MessageNum.Bytes())]
 		if !ok {
-			return nil, nil, errors.New("didn't have tx data")
+			return nil, nil, errors.Errorf("log fetcher did not resolve a transaction for message %s", parsedLog.MessageNum)
 		}
 		args := make(map[string]interface{})
 		err = l2MessageFromOriginCallABI.Inputs.UnpackIntoMap(args, tx.Data()[4:])
@@ -131,9 +159,22 @@ func (r *StandardInboxWatcher) parseMessage(txData map[string]*types.Transaction
 type StandardInbox struct {
 	*StandardInboxWatcher
 	auth transactauth.TransactAuth
+	// signer, if set, is used in place of auth's in-memory signer when submitting transactions, so
+	// an operator can route signing to an external signer (clef, a cloud KMS, ...) without keeping
+	// the key on the same host as the sequencer. See NewStandardInboxWithSigner.
+	signer transactauth.TxSigner
+	// txSender, if set, is used to broadcast signed transactions instead of calling
+	// client.SendTransaction directly, e.g. to route through a dataposter.DataPoster.
+	txSender TxSender
 }
 
 func NewStandardInbox(address ethcommon.Address, client ethutils.EthClient, auth transactauth.TransactAuth) (*StandardInbox, error) {
+	return NewStandardInboxWithSigner(address, client, auth, nil)
+}
+
+// NewStandardInboxWithSigner is like NewStandardInbox, but lets the caller supply a TxSigner to
+// sign outgoing transactions with instead of auth's own in-memory signer.
+func NewStandardInboxWithSigner(address ethcommon.Address, client ethutils.EthClient, auth transactauth.TransactAuth, signer transactauth.TxSigner) (*StandardInbox, error) {
 	watcher, err := NewStandardInboxWatcher(address, client)
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -141,17 +182,87 @@ func NewStandardInbox(address ethcommon.Address, client ethutils.EthClient, auth
 	return &StandardInbox{
 		StandardInboxWatcher: watcher,
 		auth:                 auth,
+		signer:               signer,
 	}, nil
 }
 
+// SetTxSender installs a TxSender that SendL2MessageFromOrigin will route signed transactions
+// through instead of sending them directly, whether or not a Signer is also set.
+func (s *StandardInbox) SetTxSender(txSender TxSender) {
+	s.txSender = txSender
+}
+
 func (s *StandardInbox) Sender() common.Address {
 	return common.NewAddressFromEth(s.auth.From())
 }
 
 func (s *StandardInbox) SendL2MessageFromOrigin(ctx context.Context, data []byte) (*arbtransaction.ArbTransaction, error) {
-	return transactauth.MakeTx(ctx, s.auth, func(auth *bind.TransactOpts) (*types.Transaction, error) {
-		return s.con.SendL2MessageFromOrigin(auth, data)
+	if s.signer == nil && s.txSender == nil {
+		return transactauth.MakeTx(ctx, s.auth, func(auth *bind.TransactOpts) (*types.Transaction, error) {
+			return s.con.SendL2MessageFromOrigin(auth, data)
+		})
+	}
+	// Build the transaction manually and sign it once via the pluggable signer (or rawAuth's own
+	// signer, if none is set), the way SendL2MessageFromOriginWithAuth does, instead of going
+	// through bind.TransactOpts.NoSend: go-ethereum's bind package signs with rawAuth's own local
+	// signer before returning even when NoSend is set, so that path would still require (and
+	// exercise) a local private key, defeating the point of a remote/KMS signer. This path is also
+	// what lets a configured txSender take over broadcasting even with the default local signer.
+	rawAuth := s.auth.GetAuth(ctx)
+	chainID, err := s.client.ChainID(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	latestHeader, err := s.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	inputs, err := l2MessageFromOriginCallABI.Inputs.Pack(data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	callData := append([]byte{}, l2MessageFromOriginCallABI.ID...)
+	callData = append(callData, inputs...)
+
+	gasTipCap := big.NewInt(15e8) // 1.5 gwei, matching SendL2MessageFromOriginWithAuth's default
+	gasFeeCap := new(big.Int).Mul(latestHeader.BaseFee, big.NewInt(2))
+	gasFeeCap.Add(gasFeeCap, gasTipCap)
+
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     rawAuth.Nonce.Uint64(),
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+		Gas:       l2MessageFromOriginBaseGasLimit + calldataGas(callData),
+		To:        &s.address,
+		Value:     big.NewInt(0),
+		Data:      callData,
 	})
+	var signedTx *types.Transaction
+	if s.signer != nil {
+		signedTx, err = s.signer.SignTx(ctx, tx)
+	} else {
+		signedTx, err = rawAuth.Signer(rawAuth.From, tx)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var arbTx *arbtransaction.ArbTransaction
+	if s.txSender != nil {
+		arbTx, err = s.txSender.Send(ctx, signedTx)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if err := s.client.SendTransaction(ctx, signedTx); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		arbTx = arbtransaction.NewArbTransaction(signedTx)
+	}
+	// rawAuth's nonce isn't auto-advanced after a manually-built transaction; bump it so the next
+	// call doesn't reuse it.
+	rawAuth.Nonce.Add(rawAuth.Nonce, big.NewInt(1))
+	return arbTx, nil
 }
 
 func AddSequencerL2BatchFromOrigin(
@@ -188,8 +299,95 @@ const smallerAddSequencerBatchGasLimit uint64 = 1_000_000
 
 var maxGasChargeWei *big.Int = big.NewInt(175e16) // 1.75 ether
 
+// calldataGas returns the intrinsic gas an EVM transaction is charged for carrying data as
+// calldata, per the standard 4/16 gas-per-byte schedule.
+func calldataGas(data []byte) uint64 {
+	var dataGas uint64
+	for _, b := range data {
+		if b == 0 {
+			dataGas += 4
+		} else {
+			dataGas += 16
+		}
+	}
+	return dataGas
+}
+
+// computeGasFeeCapAndLimit derives a gas fee cap and gas limit for a sequencer batch transaction
+// from the parent header and the gas the batch's calldata will consume, shrinking the gas limit
+// if necessary to keep the total gas charge under maxGasChargeWei. gasTipCap is returned unchanged
+// so callers that already picked one (or got it from a FeeEstimator) can reuse this purely for the
+// fee cap / gas limit tradeoff.
+func computeGasFeeCapAndLimit(latestHeader *types.Header, gasTipCap *big.Int, baseGasLimit, dataGas uint64) (gasFeeCap *big.Int, gasLimit uint64) {
+	gasLimit = baseGasLimit + dataGas
+	gasFeeCap = new(big.Int).Mul(latestHeader.BaseFee, big.NewInt(2))
+	gasFeeCap.Add(gasFeeCap, gasTipCap)
+	gasCharge := new(big.Int).Mul(gasFeeCap, new(big.Int).SetUint64(gasLimit))
+	if gasCharge.Cmp(maxGasChargeWei) > 0 {
+		// try to reduce the gas charge by setting the gas fee cap to 3/2 the base fee
+		gasFeeCap.Mul(latestHeader.BaseFee, big.NewInt(3))
+		gasFeeCap.Div(gasFeeCap, big.NewInt(2))
+		gasFeeCap.Add(gasFeeCap, gasTipCap)
+		gasCharge.Mul(gasFeeCap, new(big.Int).SetUint64(gasLimit))
+	}
+	if gasCharge.Cmp(maxGasChargeWei) > 0 {
+		// try to reduce the gas charge by using a lower gas limit
+		gasLimit = smallerAddSequencerBatchGasLimit + dataGas
+	}
+	return gasFeeCap, gasLimit
+}
+
+// TxSender broadcasts an already-signed transaction. Where none is configured, callers call
+// ethutils.EthClient.SendTransaction directly; a TxSender is for anything that wants to take
+// ownership of delivery instead, such as a dataposter.DataPoster that persists the transaction
+// before sending it and watches it through to confirmation.
+type TxSender interface {
+	Send(ctx context.Context, tx *types.Transaction) (*arbtransaction.ArbTransaction, error)
+}
+
+// SequencerBatchPosterConfig bundles the knobs the sequencer batch posting functions below accept,
+// so adding a new one (a FeeEstimator, a TxSigner, ...) doesn't mean growing their parameter lists
+// again. The zero value is not valid; start from DefaultSequencerBatchPosterConfig.
+type SequencerBatchPosterConfig struct {
+	// Estimator picks the gas tip cap to target; defaults to DefaultFixedFeeEstimator.
+	Estimator FeeEstimator
+	// Signer, if set, signs the batch transaction in place of auth's in-memory signer, so signing
+	// can be routed to an external signer (see the transactauth.TxSigner implementations).
+	Signer transactauth.TxSigner
+	// Sender, if set, is used to broadcast the signed transaction instead of calling
+	// client.SendTransaction directly.
+	Sender TxSender
+}
+
+var DefaultSequencerBatchPosterConfig = SequencerBatchPosterConfig{
+	Estimator: DefaultFixedFeeEstimator,
+}
+
+// signTx signs tx via cfg.Signer if one is set, falling back to rawAuth's own signer otherwise.
+func (cfg SequencerBatchPosterConfig) signTx(ctx context.Context, rawAuth *bind.TransactOpts, tx *types.Transaction) (*types.Transaction, error) {
+	if cfg.Signer != nil {
+		return cfg.Signer.SignTx(ctx, tx)
+	}
+	return rawAuth.Signer(rawAuth.From, tx)
+}
+
+// send broadcasts tx via cfg.Sender if one is set, falling back to calling client.SendTransaction
+// and wrapping the result directly otherwise.
+func (cfg SequencerBatchPosterConfig) send(ctx context.Context, client ethutils.EthClient, tx *types.Transaction) (*arbtransaction.ArbTransaction, error) {
+	if cfg.Sender != nil {
+		return cfg.Sender.Send(ctx, tx)
+	}
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+	return arbtransaction.NewArbTransaction(tx), nil
+}
+
 // AddSequencerL2BatchFromOriginCustomNonce is like AddSequencerL2BatchFromOrigin but with a custom nonce that will
 // be incremented on success.  This is to handle the case when a stuck transaction is present on startup.
+// It uses DefaultSequencerBatchPosterConfig for backwards compatibility; callers that want an
+// adaptive fee estimator or a pluggable signer should call
+// AddSequencerL2BatchFromOriginCustomNonceWithConfig directly.
 func AddSequencerL2BatchFromOriginCustomNonce(
 	ctx context.Context,
 	client ethutils.EthClient,
@@ -202,6 +400,28 @@ func AddSequencerL2BatchFromOriginCustomNonce(
 	afterAcc [32]byte,
 	gasRefunder ethcommon.Address,
 	gasRefunderExtraGas uint64,
+) (*arbtransaction.ArbTransaction, error) {
+	return AddSequencerL2BatchFromOriginCustomNonceWithConfig(
+		ctx, client, seqInboxAddr, auth, nonce, transactions, lengths, sectionsMetadata, afterAcc,
+		gasRefunder, gasRefunderExtraGas, DefaultSequencerBatchPosterConfig,
+	)
+}
+
+// AddSequencerL2BatchFromOriginCustomNonceWithConfig is AddSequencerL2BatchFromOriginCustomNonce but
+// lets the caller override fee estimation and signing via cfg.
+func AddSequencerL2BatchFromOriginCustomNonceWithConfig(
+	ctx context.Context,
+	client ethutils.EthClient,
+	seqInboxAddr common.Address,
+	auth transactauth.TransactAuth,
+	nonce *big.Int,
+	transactions []byte,
+	lengths []*big.Int,
+	sectionsMetadata []*big.Int,
+	afterAcc [32]byte,
+	gasRefunder ethcommon.Address,
+	gasRefunderExtraGas uint64,
+	cfg SequencerBatchPosterConfig,
 ) (*arbtransaction.ArbTransaction, error) {
 	rawAuth := auth.GetAuth(ctx)
 	latestHeader, err := client.HeaderByNumber(ctx, nil)
@@ -215,32 +435,12 @@ func AddSequencerL2BatchFromOriginCustomNonce(
 	}
 	data := append([]byte{}, method.ID...)
 	data = append(data, inputs...)
-	var dataGas uint64
-	for _, b := range data {
-		if b == 0 {
-			dataGas += 4
-		} else {
-			dataGas += 16
-		}
-	}
 	to := seqInboxAddr.ToEthAddress()
-	gasLimit := addSequencerBatchGasLimit + dataGas
-	gasFeeCap := new(big.Int).Mul(latestHeader.BaseFee, big.NewInt(2))
-	gasTipCap := big.NewInt(15e8) // 1.5 gwei
-	gasFeeCap.Add(gasFeeCap, gasTipCap)
-	gasCharge := new(big.Int).Mul(gasFeeCap, new(big.Int).SetUint64(gasLimit))
-	if gasCharge.Cmp(maxGasChargeWei) > 0 {
-		// try to reduce the gas charge by setting the gas fee cap to 3/2 the base fee
-		gasFeeCap.Mul(latestHeader.BaseFee, big.NewInt(3))
-		gasFeeCap.Div(gasFeeCap, big.NewInt(2))
-		gasFeeCap.Add(gasFeeCap, gasTipCap)
-		gasCharge.Mul(gasFeeCap, new(big.Int).SetUint64(gasLimit))
-	}
-	if gasCharge.Cmp(maxGasChargeWei) > 0 {
-		// try to reduce the gas charge by using a lower gas limit
-		gasLimit = smallerAddSequencerBatchGasLimit + dataGas
-		gasCharge.Mul(gasFeeCap, new(big.Int).SetUint64(gasLimit))
+	gasTipCap, err := cfg.Estimator.EstimateFees(ctx, latestHeader)
+	if err != nil {
+		return nil, err
 	}
+	gasFeeCap, gasLimit := computeGasFeeCapAndLimit(latestHeader, gasTipCap, addSequencerBatchGasLimit, calldataGas(data))
 	tx := types.NewTx(&types.DynamicFeeTx{
 		Nonce:     nonce.Uint64(),
 		GasTipCap: gasTipCap,
@@ -250,11 +450,11 @@ func AddSequencerL2BatchFromOriginCustomNonce(
 		Value:     big.NewInt(0),
 		Data:      data,
 	})
-	tx, err = rawAuth.Signer(rawAuth.From, tx)
+	tx, err = cfg.signTx(ctx, rawAuth, tx)
 	if err != nil {
 		return nil, err
 	}
-	err = client.SendTransaction(ctx, tx)
+	arbTx, err := cfg.send(ctx, client, tx)
 	if err != nil {
 		return nil, err
 	}
@@ -263,5 +463,5 @@ func AddSequencerL2BatchFromOriginCustomNonce(
 		rawAuth.Nonce.Set(nonce)
 	}
 
-	return arbtransaction.NewArbTransaction(tx), nil
+	return arbTx, nil
 }
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/holiman/uint256"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arbtransaction"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/transactauth"
+)
+
+// these sizes come from the 4844 spec: a blob is 4096 BLS12-381 field elements, each 32 bytes wide.
+const blobFieldElements = 4096
+const bytesPerFieldElement = 32
+
+// usableBytesPerFieldElement leaves the top byte of every field element zero, so the element's
+// big-endian value is always below the BLS12-381 scalar field modulus without needing a real
+// modular reduction.
+const usableBytesPerFieldElement = bytesPerFieldElement - 1
+const usableBytesPerBlob = blobFieldElements * usableBytesPerFieldElement
+
+// maxBlobsPerTx is the per-transaction blob count enforced by the protocol as of Cancun.
+const maxBlobsPerTx = 6
+
+// AddSequencerL2BatchBlobsConfig controls when AddSequencerL2BatchBlobs is willing to post a batch
+// as blobs rather than falling back to the calldata path.
+type AddSequencerL2BatchBlobsConfig struct {
+	// MaxBlobFeeChargeWei caps the total blob fee (BlobFeeCap * blob count) this call will accept;
+	// above it we fall back to AddSequencerL2BatchFromOriginCustomNonceWithConfig.
+	MaxBlobFeeChargeWei *big.Int
+	// Poster controls fee estimation and signing, shared with the calldata path.
+	Poster SequencerBatchPosterConfig
+}
+
+var DefaultAddSequencerL2BatchBlobsConfig = AddSequencerL2BatchBlobsConfig{
+	MaxBlobFeeChargeWei: big.NewInt(175e16), // 1.75 ether
+	Poster:              DefaultSequencerBatchPosterConfig,
+}
+
+// encodeBlobs packs transactions into one or more 4844 blobs.
+func encodeBlobs(transactions []byte) ([]kzg4844.Blob, error) {
+	if len(transactions) == 0 {
+		return nil, errors.New("no batch data to encode into blobs")
+	}
+	numBlobs := (len(transactions) + usableBytesPerBlob - 1) / usableBytesPerBlob
+	if numBlobs > maxBlobsPerTx {
+		return nil, errors.Errorf("batch requires %d blobs, more than the %d allowed per transaction", numBlobs, maxBlobsPerTx)
+	}
+	blobs := make([]kzg4844.Blob, numBlobs)
+	for b := 0; b < numBlobs; b++ {
+		for fe := 0; fe < blobFieldElements; fe++ {
+			start := b*usableBytesPerBlob + fe*usableBytesPerFieldElement
+			if start >= len(transactions) {
+				break
+			}
+			end := start + usableBytesPerFieldElement
+			if end > len(transactions) {
+				end = len(transactions)
+			}
+			// leave byte 0 of the field element zero; fill the remaining 31 bytes with data
+			copy(blobs[b][fe*bytesPerFieldElement+1:], transactions[start:end])
+		}
+	}
+	return blobs, nil
+}
+
+// blobTxSidecar computes the KZG commitments, proofs, and versioned hashes go-ethereum requires to
+// submit a BlobTx carrying the given blobs.
+func blobTxSidecar(blobs []kzg4844.Blob) (*types.BlobTxSidecar, []ethcommon.Hash, error) {
+	sidecar := &types.BlobTxSidecar{Blobs: blobs}
+	versionedHashes := make([]ethcommon.Hash, len(blobs))
+	for i := range blobs {
+		commitment, err := kzg4844.BlobToCommitment(&blobs[i])
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		proof, err := kzg4844.ComputeBlobProof(&blobs[i], commitment)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		sidecar.Commitments = append(sidecar.Commitments, commitment)
+		sidecar.Proofs = append(sidecar.Proofs, proof)
+		versionedHashes[i] = kzg4844.CalcBlobHashV1(sha256.New(), &commitment)
+	}
+	return sidecar, versionedHashes, nil
+}
+
+// AddSequencerL2BatchBlobs posts a sequencer batch as an EIP-4844 blob transaction, falling back
+// to AddSequencerL2BatchFromOriginCustomNonce's calldata path when the target chain isn't
+// blob-aware yet or when the current blob fee makes blobs more expensive than cfg allows.
+func AddSequencerL2BatchBlobs(
+	ctx context.Context,
+	client ethutils.EthClient,
+	seqInboxAddr common.Address,
+	auth transactauth.TransactAuth,
+	nonce *big.Int,
+	transactions []byte,
+	lengths []*big.Int,
+	sectionsMetadata []*big.Int,
+	afterAcc [32]byte,
+	gasRefunder ethcommon.Address,
+	blobAware bool,
+	cfg AddSequencerL2BatchBlobsConfig,
+) (*arbtransaction.ArbTransaction, error) {
+	latestHeader, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	blobs, blobFeeCap, err := prepareBlobFee(latestHeader, transactions, cfg)
+	if !blobAware || err != nil {
+		return AddSequencerL2BatchFromOriginCustomNonceWithConfig(ctx, client, seqInboxAddr, auth, nonce, transactions, lengths, sectionsMetadata, afterAcc, gasRefunder, 0, cfg.Poster)
+	}
+
+	sidecar, versionedHashes, err := blobTxSidecar(blobs)
+	if err != nil {
+		return nil, err
+	}
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	method := sequencerInboxABI.Methods["addSequencerL2BatchFromBlobs"]
+	inputs, err := method.Inputs.Pack(lengths, sectionsMetadata, afterAcc, gasRefunder)
+	if err != nil {
+		return nil, err
+	}
+	data := append([]byte{}, method.ID...)
+	data = append(data, inputs...)
+
+	rawAuth := auth.GetAuth(ctx)
+	to := seqInboxAddr.ToEthAddress()
+	gasTipCap, err := cfg.Poster.Estimator.EstimateFees(ctx, latestHeader)
+	if err != nil {
+		return nil, err
+	}
+	gasFeeCap, gasLimit := computeGasFeeCapAndLimit(latestHeader, gasTipCap, addSequencerBatchGasLimit, calldataGas(data))
+
+	tx := types.NewTx(&types.BlobTx{
+		ChainID:    uint256.MustFromBig(chainID),
+		Nonce:      nonce.Uint64(),
+		GasTipCap:  uint256.MustFromBig(gasTipCap),
+		GasFeeCap:  uint256.MustFromBig(gasFeeCap),
+		Gas:        gasLimit,
+		To:         to,
+		Value:      uint256.NewInt(0),
+		Data:       data,
+		BlobFeeCap: uint256.MustFromBig(blobFeeCap),
+		BlobHashes: versionedHashes,
+		Sidecar:    sidecar,
+	})
+	tx, err = cfg.Poster.signTx(ctx, rawAuth, tx)
+	if err != nil {
+		return nil, err
+	}
+	arbTx, err := cfg.Poster.send(ctx, client, tx)
+	if err != nil {
+		return nil, err
+	}
+	nonce.Add(nonce, big.NewInt(1))
+	if rawAuth.Nonce.Cmp(nonce) < 0 {
+		rawAuth.Nonce.Set(nonce)
+	}
+
+	return arbTx, nil
+}
+
+// prepareBlobFee encodes transactions into blobs and computes the blob fee cap implied by
+// latestHeader, returning an error if blobbing isn't viable (no excess blob gas tracked yet, or
+// the total blob fee exceeds cfg.MaxBlobFeeChargeWei) so the caller can fall back to calldata.
+func prepareBlobFee(latestHeader *types.Header, transactions []byte, cfg AddSequencerL2BatchBlobsConfig) ([]kzg4844.Blob, *big.Int, error) {
+	if latestHeader.ExcessBlobGas == nil {
+		return nil, nil, errors.New("parent header has no excess blob gas; chain is not blob-aware")
+	}
+	blobs, err := encodeBlobs(transactions)
+	if err != nil {
+		return nil, nil, err
+	}
+	blobFeeCap := eip4844.CalcBlobFee(*latestHeader.ExcessBlobGas)
+	totalBlobFee := new(big.Int).Mul(blobFeeCap, big.NewInt(int64(len(blobs))))
+	if totalBlobFee.Cmp(cfg.MaxBlobFeeChargeWei) > 0 {
+		return nil, nil, errors.Errorf("total blob fee %s exceeds max %s", totalBlobFee, cfg.MaxBlobFeeChargeWei)
+	}
+	return blobs, blobFeeCap, nil
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"bytes"
+	"testing"
+)
+
+// decodeBlobs is encodeBlobs's inverse: it strips the zero top byte of every field element and
+// concatenates the remaining 31 bytes back into the original data, including whatever zero padding
+// encodeBlobs added to fill out the last field element.
+func decodeBlobs(blobs [][]byte) []byte {
+	var out []byte
+	for _, blob := range blobs {
+		for fe := 0; fe+bytesPerFieldElement <= len(blob); fe += bytesPerFieldElement {
+			out = append(out, blob[fe+1:fe+bytesPerFieldElement]...)
+		}
+	}
+	return out
+}
+
+func TestEncodeBlobsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"single byte", 1},
+		{"one field element", usableBytesPerFieldElement},
+		{"one field element plus one byte", usableBytesPerFieldElement + 1},
+		{"exactly one blob", usableBytesPerBlob},
+		{"spills into a second blob", usableBytesPerBlob + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.size)
+			for i := range data {
+				data[i] = byte(i%255) + 1 // avoid zero bytes so padding is distinguishable
+			}
+
+			blobs, err := encodeBlobs(data)
+			if err != nil {
+				t.Fatalf("encodeBlobs: %v", err)
+			}
+
+			wantBlobs := (tt.size + usableBytesPerBlob - 1) / usableBytesPerBlob
+			if len(blobs) != wantBlobs {
+				t.Fatalf("got %d blobs, want %d", len(blobs), wantBlobs)
+			}
+
+			rawBlobs := make([][]byte, len(blobs))
+			for i := range blobs {
+				rawBlobs[i] = blobs[i][:]
+				for fe := 0; fe < blobFieldElements; fe++ {
+					if blobs[i][fe*bytesPerFieldElement] != 0 {
+						t.Fatalf("blob %d field element %d has a nonzero top byte", i, fe)
+					}
+				}
+			}
+
+			decoded := decodeBlobs(rawBlobs)
+			if !bytes.Equal(decoded[:len(data)], data) {
+				t.Fatalf("decoded data doesn't match input: got %x, want %x", decoded[:len(data)], data)
+			}
+		})
+	}
+}
+
+func TestEncodeBlobsEmptyInput(t *testing.T) {
+	if _, err := encodeBlobs(nil); err == nil {
+		t.Fatal("expected an error encoding no data, got nil")
+	}
+}
+
+func TestEncodeBlobsTooLarge(t *testing.T) {
+	data := make([]byte, usableBytesPerBlob*(maxBlobsPerTx+1))
+	if _, err := encodeBlobs(data); err == nil {
+		t.Fatal("expected an error when data requires more than maxBlobsPerTx blobs, got nil")
+	}
+}
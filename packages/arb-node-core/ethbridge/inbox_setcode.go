@@ -0,0 +1,134 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"context"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arbtransaction"
+)
+
+// l2MessageFromOriginBaseGasLimit is a conservative estimate of sendL2MessageFromOrigin's own
+// execution cost, excluding calldata and EIP-7702 authorization processing, used because
+// eth_estimateGas can't be relied on for every client's handling of type-0x04 transactions yet.
+const l2MessageFromOriginBaseGasLimit uint64 = 100_000
+
+// SendL2MessageFromOriginWithAuth is like SendL2MessageFromOrigin, but submits the call as an
+// EIP-7702 SetCodeTx (type 0x04) carrying authorizations, so an EOA sender can temporarily delegate
+// execution to a contract while submitting the L2 message. authorizations must be signed with
+// transactauth.Authorizer beforehand; at least one is required.
+func (s *StandardInbox) SendL2MessageFromOriginWithAuth(ctx context.Context, data []byte, authorizations []types.SetCodeAuthorization) (*arbtransaction.ArbTransaction, error) {
+	if len(authorizations) == 0 {
+		return nil, errors.New("SendL2MessageFromOriginWithAuth requires at least one authorization")
+	}
+	rawAuth := s.auth.GetAuth(ctx)
+	if err := validateAuthorizationNonces(rawAuth.From, rawAuth.Nonce.Uint64(), authorizations); err != nil {
+		return nil, err
+	}
+
+	chainID, err := s.client.ChainID(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	latestHeader, err := s.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	inputs, err := l2MessageFromOriginCallABI.Inputs.Pack(data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	callData := append([]byte{}, l2MessageFromOriginCallABI.ID...)
+	callData = append(callData, inputs...)
+
+	// Charge PerAuthBaseCost for each authorization, plus PerEmptyAccountCost since we can't cheaply
+	// check on-chain whether the authority account already exists; this overcharges rather than
+	// risks an out-of-gas revert.
+	authGas := uint64(len(authorizations)) * (params.PerAuthBaseCost + params.PerEmptyAccountCost)
+	gasLimit := l2MessageFromOriginBaseGasLimit + calldataGas(callData) + authGas
+
+	gasTipCap := big.NewInt(15e8) // 1.5 gwei
+	gasFeeCap := new(big.Int).Mul(latestHeader.BaseFee, big.NewInt(2))
+	gasFeeCap.Add(gasFeeCap, gasTipCap)
+
+	tx := types.NewTx(&types.SetCodeTx{
+		ChainID:   uint256.MustFromBig(chainID),
+		Nonce:     rawAuth.Nonce.Uint64(),
+		GasTipCap: uint256.MustFromBig(gasTipCap),
+		GasFeeCap: uint256.MustFromBig(gasFeeCap),
+		Gas:       gasLimit,
+		To:        s.address,
+		Value:     uint256.NewInt(0),
+		Data:      callData,
+		AuthList:  authorizations,
+	})
+
+	var signedTx *types.Transaction
+	if s.signer != nil {
+		signedTx, err = s.signer.SignTx(ctx, tx)
+	} else {
+		signedTx, err = rawAuth.Signer(rawAuth.From, tx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var arbTx *arbtransaction.ArbTransaction
+	if s.txSender != nil {
+		arbTx, err = s.txSender.Send(ctx, signedTx)
+	} else {
+		if err = s.client.SendTransaction(ctx, signedTx); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		arbTx = arbtransaction.NewArbTransaction(signedTx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// rawAuth's nonce isn't auto-advanced after a manually-built transaction like this one; bump it
+	// so a second call through auth (via this method or SendL2MessageFromOrigin) doesn't reuse it.
+	rawAuth.Nonce.Add(rawAuth.Nonce, big.NewInt(1))
+	return arbTx, nil
+}
+
+// validateAuthorizationNonces enforces the EIP-7702 invariant that when the transaction's own
+// sender is also one of the authorizing accounts, that authorization's nonce must equal the
+// transaction's nonce: the sender's on-chain nonce hasn't been bumped yet when authorizations are
+// validated, even though the tx itself is about to consume it.
+func validateAuthorizationNonces(sender ethcommon.Address, txNonce uint64, authorizations []types.SetCodeAuthorization) error {
+	for _, authorization := range authorizations {
+		authority, err := authorization.Authority()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if authority == sender && authorization.Nonce != txNonce {
+			return errors.Errorf(
+				"authorization from the sender account must use nonce %d (the transaction's own nonce), got %d",
+				txNonce, authorization.Nonce,
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/transactauth"
+)
+
+var testAuthChainID = big.NewInt(42161)
+
+func newTestAuthorizer(t *testing.T) *transactauth.Authorizer {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return transactauth.NewAuthorizer(key)
+}
+
+func TestValidateAuthorizationNoncesRequiresSenderNonceMatch(t *testing.T) {
+	senderAuthorizer := newTestAuthorizer(t)
+	sender := senderAuthorizer.Authority()
+	delegate := ethcommon.HexToAddress("0x3333333333333333333333333333333333333333")
+
+	// The sender authorizing itself with the wrong nonce (not the tx's own nonce) must be rejected.
+	mismatched, err := senderAuthorizer.Authorize(testAuthChainID, delegate, 41)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if err := validateAuthorizationNonces(sender, 42, []types.SetCodeAuthorization{mismatched}); err == nil {
+		t.Fatal("expected an error when the sender's own authorization nonce doesn't match the tx nonce, got nil")
+	}
+
+	// The matching nonce (the tx's own nonce) must be accepted.
+	matched, err := senderAuthorizer.Authorize(testAuthChainID, delegate, 42)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if err := validateAuthorizationNonces(sender, 42, []types.SetCodeAuthorization{matched}); err != nil {
+		t.Errorf("expected no error when the sender's authorization nonce matches the tx nonce, got %v", err)
+	}
+}
+
+func TestValidateAuthorizationNoncesIgnoresOtherAccounts(t *testing.T) {
+	senderAuthorizer := newTestAuthorizer(t)
+	otherAuthorizer := newTestAuthorizer(t)
+	sender := senderAuthorizer.Authority()
+	delegate := ethcommon.HexToAddress("0x4444444444444444444444444444444444444444")
+
+	// An authorization from an account other than the sender isn't subject to the tx-nonce
+	// constraint, so any nonce should be accepted regardless of the tx's own nonce.
+	authorization, err := otherAuthorizer.Authorize(testAuthChainID, delegate, 999)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if err := validateAuthorizationNonces(sender, 42, []types.SetCodeAuthorization{authorization}); err != nil {
+		t.Errorf("expected no error for a non-sender authorization with an unrelated nonce, got %v", err)
+	}
+}
+
+func TestValidateAuthorizationNoncesMultipleAuthorizations(t *testing.T) {
+	senderAuthorizer := newTestAuthorizer(t)
+	otherAuthorizer := newTestAuthorizer(t)
+	sender := senderAuthorizer.Authority()
+	delegate := ethcommon.HexToAddress("0x5555555555555555555555555555555555555555")
+
+	senderAuth, err := senderAuthorizer.Authorize(testAuthChainID, delegate, 7)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	otherAuth, err := otherAuthorizer.Authorize(testAuthChainID, delegate, 1000)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	if err := validateAuthorizationNonces(sender, 7, []types.SetCodeAuthorization{senderAuth, otherAuth}); err != nil {
+		t.Errorf("expected a valid mixed authorization list to pass, got %v", err)
+	}
+	if err := validateAuthorizationNonces(sender, 8, []types.SetCodeAuthorization{senderAuth, otherAuth}); err == nil {
+		t.Error("expected an error when the sender's authorization no longer matches the tx nonce, got nil")
+	}
+}
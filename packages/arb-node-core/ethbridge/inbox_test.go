@@ -0,0 +1,85 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestCalldataGas(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want uint64
+	}{
+		{"empty", nil, 0},
+		{"all zero", []byte{0, 0, 0}, 12},
+		{"all nonzero", []byte{1, 2, 3}, 48},
+		{"mixed", []byte{0, 1, 0, 2}, 2*4 + 2*16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calldataGas(tt.data); got != tt.want {
+				t.Errorf("calldataGas(%v) = %d, want %d", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeGasFeeCapAndLimitUnderCap(t *testing.T) {
+	header := &types.Header{BaseFee: big.NewInt(10e9)} // 10 gwei
+	gasTipCap := big.NewInt(15e8)                       // 1.5 gwei
+
+	gasFeeCap, gasLimit := computeGasFeeCapAndLimit(header, gasTipCap, addSequencerBatchGasLimit, 0)
+
+	wantGasFeeCap := new(big.Int).Mul(header.BaseFee, big.NewInt(2))
+	wantGasFeeCap.Add(wantGasFeeCap, gasTipCap)
+	if gasFeeCap.Cmp(wantGasFeeCap) != 0 {
+		t.Errorf("gasFeeCap = %s, want %s", gasFeeCap, wantGasFeeCap)
+	}
+	if gasLimit != addSequencerBatchGasLimit {
+		t.Errorf("gasLimit = %d, want %d", gasLimit, addSequencerBatchGasLimit)
+	}
+}
+
+func TestComputeGasFeeCapAndLimitOverCap(t *testing.T) {
+	// A base fee high enough that 2*baseFee*gasLimit alone exceeds maxGasChargeWei, forcing the
+	// function through both its fallback steps: first shrinking the fee cap to 3/2 base fee, then
+	// (since that's still over) falling back to the smaller gas limit.
+	header := &types.Header{BaseFee: big.NewInt(900e9)} // 900 gwei
+	gasTipCap := big.NewInt(15e8)
+
+	gasFeeCap, gasLimit := computeGasFeeCapAndLimit(header, gasTipCap, addSequencerBatchGasLimit, 0)
+
+	wantGasFeeCap := new(big.Int).Mul(header.BaseFee, big.NewInt(3))
+	wantGasFeeCap.Div(wantGasFeeCap, big.NewInt(2))
+	wantGasFeeCap.Add(wantGasFeeCap, gasTipCap)
+	if gasFeeCap.Cmp(wantGasFeeCap) != 0 {
+		t.Errorf("gasFeeCap = %s, want %s", gasFeeCap, wantGasFeeCap)
+	}
+	if gasLimit != smallerAddSequencerBatchGasLimit {
+		t.Errorf("gasLimit = %d, want the smaller fallback limit %d", gasLimit, smallerAddSequencerBatchGasLimit)
+	}
+
+	gasCharge := new(big.Int).Mul(gasFeeCap, new(big.Int).SetUint64(gasLimit))
+	if gasCharge.Cmp(maxGasChargeWei) > 0 {
+		t.Errorf("gasCharge %s still exceeds maxGasChargeWei %s after both fallbacks", gasCharge, maxGasChargeWei)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var logCacheBucket = []byte("logs")
+
+// logCacheSeqBucket indexes logCacheBucket's entries by insertion sequence (big-endian uint64 ->
+// the logCacheBucket key that entry lives under), so evictOldest can find the globally-oldest
+// entries with a bounded cursor walk instead of scanning and unmarshaling every cached entry.
+var logCacheSeqBucket = []byte("logs_by_seq")
+
+// cachedLog is what's persisted per (address, topic, msgNum) entry.
+type cachedLog struct {
+	Log   types.Log
+	RawTx []byte // tx.MarshalBinary(), nil if the log's topic has no associated transaction
+	Seq   uint64 // sequence number of the put that wrote this entry; eviction order is read from logCacheSeqBucket, not from this field
+}
+
+// logCache is an on-disk cache of previously fetched inbox logs (and, where applicable, the
+// transaction that produced them), keyed by (address, topic, message number), so that re-scanning
+// the same block range after a restart doesn't re-issue FilterLogs/TransactionByHash calls for
+// messages already seen. It evicts by insertion order once capacity is exceeded, which approximates
+// LRU well enough here since entries are only ever read, never refreshed.
+type logCache struct {
+	db       *bolt.DB
+	capacity int
+
+	mu      sync.Mutex
+	nextSeq uint64
+	size    int
+}
+
+func openLogCache(path string, capacity int) (*logCache, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c := &logCache{db: db, capacity: capacity}
+	if err := c.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(logCacheBucket)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		seqBucket, err := tx.CreateBucketIfNotExists(logCacheSeqBucket)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		c.size = bucket.Stats().KeyN
+		if lastSeq, _ := seqBucket.Cursor().Last(); lastSeq != nil {
+			c.nextSeq = binary.BigEndian.Uint64(lastSeq) + 1
+		}
+		return nil
+	}); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func logCacheKey(address ethcommon.Address, topic ethcommon.Hash, msgNum *big.Int) []byte {
+	return []byte(fmt.Sprintf("%s|%s|%s", address.Hex(), topic.Hex(), msgNum.String()))
+}
+
+// seqKey encodes seq as a fixed-width big-endian key, so logCacheSeqBucket's cursor order matches
+// insertion order.
+func seqKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// get returns the cached log and (if present) its transaction for the given key, or ok=false on a
+// cache miss.
+func (c *logCache) get(address ethcommon.Address, topic ethcommon.Hash, msgNum *big.Int) (types.Log, *types.Transaction, bool, error) {
+	key := logCacheKey(address, topic, msgNum)
+	var entry cachedLog
+	found := false
+	err := c.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(logCacheBucket).Get(key)
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &entry)
+	})
+	if err != nil {
+		return types.Log{}, nil, false, errors.WithStack(err)
+	}
+	if !found {
+		return types.Log{}, nil, false, nil
+	}
+	var txn *types.Transaction
+	if len(entry.RawTx) > 0 {
+		txn = new(types.Transaction)
+		if err := txn.UnmarshalBinary(entry.RawTx); err != nil {
+			return types.Log{}, nil, false, errors.WithStack(err)
+		}
+	}
+	return entry.Log, txn, true, nil
+}
+
+// put stores log (and its transaction, if any) under the given key, evicting the oldest entries
+// once the cache is over capacity.
+func (c *logCache) put(address ethcommon.Address, topic ethcommon.Hash, msgNum *big.Int, log types.Log, txn *types.Transaction) error {
+	var rawTx []byte
+	if txn != nil {
+		var err error
+		rawTx, err = txn.MarshalBinary()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	c.mu.Lock()
+	seq := c.nextSeq
+	c.nextSeq++
+	c.mu.Unlock()
+
+	entry := cachedLog{Log: log, RawTx: rawTx, Seq: seq}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(logCacheBucket)
+		key := logCacheKey(address, topic, msgNum)
+		isNew := bucket.Get(key) == nil
+		if err := bucket.Put(key, data); err != nil {
+			return errors.WithStack(err)
+		}
+		if !isNew {
+			return nil
+		}
+		if err := tx.Bucket(logCacheSeqBucket).Put(seqKey(seq), key); err != nil {
+			return errors.WithStack(err)
+		}
+		c.mu.Lock()
+		c.size++
+		overCapacity := c.size - c.capacity
+		c.mu.Unlock()
+		if overCapacity <= 0 {
+			return nil
+		}
+		return c.evictOldest(tx, overCapacity)
+	})
+}
+
+// evictOldest removes the count entries with the lowest Seq, found via logCacheSeqBucket's cursor
+// order rather than scanning and unmarshaling every entry in logCacheBucket; called with a write
+// transaction already open from put.
+func (c *logCache) evictOldest(tx *bolt.Tx, count int) error {
+	bucket := tx.Bucket(logCacheBucket)
+	seqBucket := tx.Bucket(logCacheSeqBucket)
+	cursor := seqBucket.Cursor()
+	seqKeysToDelete := make([][]byte, 0, count)
+	for sk, key := cursor.First(); sk != nil && len(seqKeysToDelete) < count; sk, key = cursor.Next() {
+		if err := bucket.Delete(key); err != nil {
+			return errors.WithStack(err)
+		}
+		seqKeysToDelete = append(seqKeysToDelete, append([]byte{}, sk...))
+		c.mu.Lock()
+		c.size--
+		c.mu.Unlock()
+	}
+	for _, sk := range seqKeysToDelete {
+		if err := seqBucket.Delete(sk); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (c *logCache) Close() error {
+	return c.db.Close()
+}
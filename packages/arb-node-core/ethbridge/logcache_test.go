@@ -0,0 +1,173 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func openTestLogCache(t *testing.T, capacity int) *logCache {
+	t.Helper()
+	c, err := openLogCache(filepath.Join(t.TempDir(), "logcache.db"), capacity)
+	if err != nil {
+		t.Fatalf("openLogCache: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func testLogCacheAddr(n int64) ethcommon.Address {
+	return ethcommon.BigToAddress(big.NewInt(n))
+}
+
+func TestLogCacheGetMiss(t *testing.T) {
+	c := openTestLogCache(t, 10)
+	_, _, ok, err := c.get(testLogCacheAddr(1), ethcommon.Hash{}, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if ok {
+		t.Fatal("get on an empty cache returned ok=true")
+	}
+}
+
+func TestLogCachePutGet(t *testing.T) {
+	c := openTestLogCache(t, 10)
+	addr := testLogCacheAddr(1)
+	topic := ethcommon.HexToHash("0x1")
+	msgNum := big.NewInt(5)
+	log := types.Log{BlockNumber: 100}
+
+	if err := c.put(addr, topic, msgNum, log, nil); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	got, txn, ok, err := c.get(addr, topic, msgNum)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected a cache hit after put")
+	}
+	if got.BlockNumber != log.BlockNumber {
+		t.Errorf("BlockNumber = %d, want %d", got.BlockNumber, log.BlockNumber)
+	}
+	if txn != nil {
+		t.Error("expected a nil transaction when none was stored")
+	}
+}
+
+func TestLogCacheEvictsOldestOnceOverCapacity(t *testing.T) {
+	c := openTestLogCache(t, 3)
+	topic := ethcommon.HexToHash("0x1")
+
+	for i := int64(0); i < 5; i++ {
+		addr := testLogCacheAddr(i)
+		if err := c.put(addr, topic, big.NewInt(i), types.Log{BlockNumber: uint64(i)}, nil); err != nil {
+			t.Fatalf("put(%d): %v", i, err)
+		}
+	}
+
+	if c.size != 3 {
+		t.Fatalf("size = %d, want 3 (capped at capacity)", c.size)
+	}
+
+	// The two oldest entries (0 and 1) should have been evicted, leaving 2, 3, 4.
+	for i := int64(0); i < 2; i++ {
+		_, _, ok, err := c.get(testLogCacheAddr(i), topic, big.NewInt(i))
+		if err != nil {
+			t.Fatalf("get(%d): %v", i, err)
+		}
+		if ok {
+			t.Errorf("entry %d still present after it should have been evicted", i)
+		}
+	}
+	for i := int64(2); i < 5; i++ {
+		_, _, ok, err := c.get(testLogCacheAddr(i), topic, big.NewInt(i))
+		if err != nil {
+			t.Fatalf("get(%d): %v", i, err)
+		}
+		if !ok {
+			t.Errorf("entry %d missing, expected it to survive eviction", i)
+		}
+	}
+}
+
+func TestLogCachePutSameKeyDoesNotDoubleCount(t *testing.T) {
+	c := openTestLogCache(t, 10)
+	addr := testLogCacheAddr(1)
+	topic := ethcommon.HexToHash("0x1")
+	msgNum := big.NewInt(1)
+
+	if err := c.put(addr, topic, msgNum, types.Log{BlockNumber: 1}, nil); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := c.put(addr, topic, msgNum, types.Log{BlockNumber: 2}, nil); err != nil {
+		t.Fatalf("put (overwrite): %v", err)
+	}
+	if c.size != 1 {
+		t.Fatalf("size = %d, want 1 (re-putting the same key shouldn't grow the cache)", c.size)
+	}
+	got, _, ok, err := c.get(addr, topic, msgNum)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if !ok || got.BlockNumber != 2 {
+		t.Errorf("get() = %+v, ok=%v, want BlockNumber=2, ok=true", got, ok)
+	}
+}
+
+func TestLogCacheReopenRecoversSizeAndNextSeq(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logcache.db")
+	c, err := openLogCache(path, 10)
+	if err != nil {
+		t.Fatalf("openLogCache: %v", err)
+	}
+	topic := ethcommon.HexToHash("0x1")
+	for i := int64(0); i < 3; i++ {
+		if err := c.put(testLogCacheAddr(i), topic, big.NewInt(i), types.Log{BlockNumber: uint64(i)}, nil); err != nil {
+			t.Fatalf("put(%d): %v", i, err)
+		}
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := openLogCache(path, 10)
+	if err != nil {
+		t.Fatalf("reopen openLogCache: %v", err)
+	}
+	defer reopened.Close()
+	if reopened.size != 3 {
+		t.Fatalf("size after reopen = %d, want 3", reopened.size)
+	}
+	if reopened.nextSeq != 3 {
+		t.Fatalf("nextSeq after reopen = %d, want 3", reopened.nextSeq)
+	}
+
+	// A put after reopening should continue the sequence rather than colliding with the old entries.
+	if err := reopened.put(testLogCacheAddr(9), topic, big.NewInt(9), types.Log{BlockNumber: 9}, nil); err != nil {
+		t.Fatalf("put after reopen: %v", err)
+	}
+	if reopened.size != 4 {
+		t.Fatalf("size after post-reopen put = %d, want 4", reopened.size)
+	}
+}
@@ -0,0 +1,355 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+// logFetcherClient is the subset of ethutils.EthClient LogFetcher needs.
+type logFetcherClient interface {
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+	TransactionByHash(ctx context.Context, txHash ethcommon.Hash) (*types.Transaction, bool, error)
+}
+
+// batchTransactionClient is implemented by clients that can batch several eth_getTransactionByHash
+// calls into a single round trip. It's checked for with a type assertion rather than required
+// directly, since not every logFetcherClient (e.g. a test fake) needs to support it; LogFetcher
+// falls back to issuing TransactionByHash calls individually when it's absent.
+type batchTransactionClient interface {
+	BatchCallContext(ctx context.Context, batch []rpc.BatchElem) error
+}
+
+// LogFetcherConfig tunes how LogFetcher shards FilterLogs ranges and bounds concurrent work.
+type LogFetcherConfig struct {
+	// MaxConcurrentShards bounds how many FilterLogs/TransactionByHash calls run at once.
+	MaxConcurrentShards int
+	// MinShardBlocks is the smallest range a shard will still split further on a "too many results"
+	// style error; below this, the error is returned as-is rather than sharding forever.
+	MinShardBlocks uint64
+	// CachePath, if set, backs an on-disk LRU cache of previously fetched logs (and their
+	// transactions) keyed by (address, topic, message number). Leave empty to disable caching.
+	CachePath string
+	// CacheCapacity caps how many entries the on-disk cache holds before evicting the oldest.
+	CacheCapacity int
+}
+
+// DefaultLogFetcherConfig shards down to individual blocks if needed and runs up to 8 shards
+// concurrently, with caching disabled; set CachePath to enable it.
+var DefaultLogFetcherConfig = LogFetcherConfig{
+	MaxConcurrentShards: 8,
+	MinShardBlocks:      1,
+	CacheCapacity:       200_000,
+}
+
+// LogFetcher resolves inbox message logs over potentially large block ranges the way providers
+// like Infura and Alchemy require: it shards a range that a provider rejects as too large into
+// smaller concurrent requests instead of failing outright, batches the follow-up transaction
+// lookups needed for origin-delivered messages, and optionally caches results on disk so a restart
+// doesn't repeat work for block ranges already scanned.
+type LogFetcher struct {
+	client logFetcherClient
+	config LogFetcherConfig
+	cache  *logCache
+	sem    chan struct{}
+}
+
+// NewLogFetcher builds a LogFetcher over client. If config.CachePath is set, it opens (creating if
+// necessary) the on-disk cache at that path; callers should call Close when done with it.
+func NewLogFetcher(client logFetcherClient, config LogFetcherConfig) (*LogFetcher, error) {
+	var cache *logCache
+	if config.CachePath != "" {
+		var err error
+		cache, err = openLogCache(config.CachePath, config.CacheCapacity)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &LogFetcher{
+		client: client,
+		config: config,
+		cache:  cache,
+		sem:    make(chan struct{}, config.MaxConcurrentShards),
+	}, nil
+}
+
+// Close releases the on-disk cache, if one is open.
+func (f *LogFetcher) Close() error {
+	if f.cache == nil {
+		return nil
+	}
+	return f.cache.Close()
+}
+
+// FetchMessageLogs resolves every log matching query whose second topic is one of msgNums,
+// sharding the block range as needed, and returns alongside it a txData map (keyed the same way
+// parseMessage expects: string(msgNum.Bytes())) populated with the transaction for every log whose
+// topic is origin-topic, so callers never have to handle a missing entry. query.Addresses must
+// contain exactly one address, since that's also the cache key's address component. If a cache is
+// configured, msgNums already resolved from it are excluded from the live query's second topic, so a
+// restart that mixes already-cached and newly-appeared message numbers doesn't re-fetch (and
+// double-return) the ones the cache already served.
+func (f *LogFetcher) FetchMessageLogs(
+	ctx context.Context,
+	query ethereum.FilterQuery,
+	msgNums []*big.Int,
+	originTopic ethcommon.Hash,
+) ([]types.Log, map[string]*types.Transaction, error) {
+	if len(query.Addresses) != 1 {
+		return nil, nil, errors.New("FetchMessageLogs requires exactly one address")
+	}
+	address := query.Addresses[0]
+
+	remaining := msgNums[:0:0]
+	var logs []types.Log
+	txData := make(map[string]*types.Transaction)
+	if f.cache != nil {
+		for _, msgNum := range msgNums {
+			hit := false
+			for _, topic := range query.Topics[0] {
+				log, txn, ok, err := f.cache.get(address, topic, msgNum)
+				if err != nil {
+					return nil, nil, err
+				}
+				if !ok {
+					continue
+				}
+				logs = append(logs, log)
+				if txn != nil {
+					txData[string(msgNum.Bytes())] = txn
+				}
+				hit = true
+				break
+			}
+			if !hit {
+				remaining = append(remaining, msgNum)
+			}
+		}
+		if len(remaining) == 0 {
+			return logs, txData, nil
+		}
+		// Narrow the live query's second topic to only the message numbers that weren't already
+		// resolved from the cache, so a restart that mixes cached and new message numbers doesn't
+		// re-fetch (and re-append) logs the cache already served.
+		remainingTopics := make([]ethcommon.Hash, len(remaining))
+		for i, msgNum := range remaining {
+			copy(remainingTopics[i][:], math.U256Bytes(msgNum))
+		}
+		query.Topics = [][]ethcommon.Hash{query.Topics[0], remainingTopics}
+	} else {
+		remaining = msgNums
+	}
+
+	fromBlock := query.FromBlock
+	if fromBlock == nil {
+		return nil, nil, errors.New("FetchMessageLogs requires a concrete FromBlock")
+	}
+	toBlock := query.ToBlock
+	if toBlock == nil {
+		return nil, nil, errors.New("FetchMessageLogs requires a concrete ToBlock")
+	}
+
+	fetched, err := f.fetchShard(ctx, query, fromBlock.Uint64(), toBlock.Uint64())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txHashes := make(map[ethcommon.Hash]struct{})
+	for _, log := range fetched {
+		if log.Topics[0] == originTopic {
+			txHashes[log.TxHash] = struct{}{}
+		}
+	}
+	fetchedTxs, err := f.fetchTransactions(ctx, txHashes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, log := range fetched {
+		logs = append(logs, log)
+		if log.Topics[0] != originTopic {
+			continue
+		}
+		msgNum := new(big.Int).SetBytes(log.Topics[1][:])
+		txn, ok := fetchedTxs[log.TxHash]
+		if !ok {
+			return nil, nil, errors.Errorf("log fetcher did not resolve a transaction for origin log %s", log.TxHash.Hex())
+		}
+		txData[string(msgNum.Bytes())] = txn
+		if f.cache != nil {
+			if err := f.cache.put(address, originTopic, msgNum, log, txn); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if f.cache != nil {
+		for _, log := range fetched {
+			if log.Topics[0] == originTopic {
+				continue
+			}
+			msgNum := new(big.Int).SetBytes(log.Topics[1][:])
+			if err := f.cache.put(address, log.Topics[0], msgNum, log, nil); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return logs, txData, nil
+}
+
+// isRangeTooLargeErr reports whether err looks like one of the "block range too large" / "too many
+// results" errors rate-limiting or range-capping providers (Infura, Alchemy, and similar) return in
+// place of actually running a FilterLogs query, rather than some unrelated failure that sharding
+// wouldn't fix.
+func isRangeTooLargeErr(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, substr := range []string{
+		"query returned more than",
+		"block range",
+		"range too large",
+		"limit exceeded",
+		"too many results",
+		"query timeout",
+		"range is too wide",
+	} {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchShard runs query over [fromBlock, toBlock], recursively halving the range and fanning the
+// halves out concurrently (bounded by f.sem) whenever the provider reports the range as too large.
+func (f *LogFetcher) fetchShard(ctx context.Context, query ethereum.FilterQuery, fromBlock, toBlock uint64) ([]types.Log, error) {
+	f.sem <- struct{}{}
+	shardQuery := query
+	shardQuery.FromBlock = new(big.Int).SetUint64(fromBlock)
+	shardQuery.ToBlock = new(big.Int).SetUint64(toBlock)
+	logs, err := f.client.FilterLogs(ctx, shardQuery)
+	<-f.sem
+	if err == nil {
+		return logs, nil
+	}
+	if fromBlock >= toBlock || toBlock-fromBlock < f.config.MinShardBlocks || !isRangeTooLargeErr(err) {
+		return nil, errors.WithStack(err)
+	}
+
+	mid := fromBlock + (toBlock-fromBlock)/2
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	var combined []types.Log
+	wg.Add(2)
+	for _, half := range [2][2]uint64{{fromBlock, mid}, {mid + 1, toBlock}} {
+		half := half
+		go func() {
+			defer wg.Done()
+			shardLogs, shardErr := f.fetchShard(ctx, query, half[0], half[1])
+			mu.Lock()
+			defer mu.Unlock()
+			if shardErr != nil {
+				if firstErr == nil {
+					firstErr = shardErr
+				}
+				return
+			}
+			combined = append(combined, shardLogs...)
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return combined, nil
+}
+
+// fetchTransactions resolves every hash in txHashes, batching the requests through
+// batchTransactionClient when the underlying client supports it, falling back to concurrent
+// individual TransactionByHash calls (bounded by f.sem) otherwise.
+func (f *LogFetcher) fetchTransactions(ctx context.Context, txHashes map[ethcommon.Hash]struct{}) (map[ethcommon.Hash]*types.Transaction, error) {
+	result := make(map[ethcommon.Hash]*types.Transaction, len(txHashes))
+	if len(txHashes) == 0 {
+		return result, nil
+	}
+
+	hashes := make([]ethcommon.Hash, 0, len(txHashes))
+	for hash := range txHashes {
+		hashes = append(hashes, hash)
+	}
+
+	if batchClient, ok := f.client.(batchTransactionClient); ok {
+		batch := make([]rpc.BatchElem, len(hashes))
+		txs := make([]*types.Transaction, len(hashes))
+		for i, hash := range hashes {
+			txs[i] = new(types.Transaction)
+			batch[i] = rpc.BatchElem{
+				Method: "eth_getTransactionByHash",
+				Args:   []interface{}{hash},
+				Result: txs[i],
+			}
+		}
+		if err := batchClient.BatchCallContext(ctx, batch); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for i, elem := range batch {
+			if elem.Error != nil {
+				return nil, errors.WithStack(elem.Error)
+			}
+			result[hashes[i]] = txs[i]
+		}
+		return result, nil
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	wg.Add(len(hashes))
+	for _, hash := range hashes {
+		hash := hash
+		go func() {
+			defer wg.Done()
+			f.sem <- struct{}{}
+			txn, _, err := f.client.TransactionByHash(ctx, hash)
+			<-f.sem
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = errors.WithStack(err)
+				}
+				return
+			}
+			result[hash] = txn
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ethbridge
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+)
+
+// fakeShardingClient simulates a provider that rejects FilterLogs over more than maxRangeBlocks
+// blocks with a "range too large" style error, returning one synthetic log per block in range
+// otherwise. It records every range it was asked to query, so tests can assert on the shards
+// fetchShard actually issued.
+type fakeShardingClient struct {
+	maxRangeBlocks uint64
+
+	mu      sync.Mutex
+	queried [][2]uint64
+}
+
+func (c *fakeShardingClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	from := query.FromBlock.Uint64()
+	to := query.ToBlock.Uint64()
+
+	c.mu.Lock()
+	c.queried = append(c.queried, [2]uint64{from, to})
+	c.mu.Unlock()
+
+	if to-from+1 > c.maxRangeBlocks {
+		return nil, errors.New("query returned more than 10000 results")
+	}
+	logs := make([]types.Log, 0, to-from+1)
+	for b := from; b <= to; b++ {
+		logs = append(logs, types.Log{BlockNumber: b})
+	}
+	return logs, nil
+}
+
+func (c *fakeShardingClient) TransactionByHash(ctx context.Context, txHash ethcommon.Hash) (*types.Transaction, bool, error) {
+	return nil, false, errors.New("not implemented")
+}
+
+func TestFetchShardSplitsOnRangeTooLarge(t *testing.T) {
+	client := &fakeShardingClient{maxRangeBlocks: 4}
+	fetcher, err := NewLogFetcher(client, LogFetcherConfig{MaxConcurrentShards: 4, MinShardBlocks: 1})
+	if err != nil {
+		t.Fatalf("NewLogFetcher: %v", err)
+	}
+
+	logs, err := fetcher.fetchShard(context.Background(), ethereum.FilterQuery{}, 1, 10)
+	if err != nil {
+		t.Fatalf("fetchShard: %v", err)
+	}
+	if len(logs) != 10 {
+		t.Fatalf("got %d logs, want 10 (one per block in [1, 10])", len(logs))
+	}
+	seen := make(map[uint64]bool)
+	for _, log := range logs {
+		seen[log.BlockNumber] = true
+	}
+	for b := uint64(1); b <= 10; b++ {
+		if !seen[b] {
+			t.Errorf("missing log for block %d", b)
+		}
+	}
+}
+
+func TestFetchShardStopsSplittingBelowMinShardBlocks(t *testing.T) {
+	// A provider that rejects every range, even a single block, should surface its error rather than
+	// sharding forever, once the range is already at MinShardBlocks.
+	client := &fakeShardingClient{maxRangeBlocks: 0}
+	fetcher, err := NewLogFetcher(client, LogFetcherConfig{MaxConcurrentShards: 4, MinShardBlocks: 1})
+	if err != nil {
+		t.Fatalf("NewLogFetcher: %v", err)
+	}
+
+	_, err = fetcher.fetchShard(context.Background(), ethereum.FilterQuery{}, 5, 5)
+	if err == nil {
+		t.Fatal("expected an error when even a single-block range is rejected, got nil")
+	}
+}
+
+func TestFetchShardNonRangeErrorIsNotSharded(t *testing.T) {
+	client := &onceErrClient{err: errors.New("connection reset by peer")}
+	fetcher, err := NewLogFetcher(client, LogFetcherConfig{MaxConcurrentShards: 4, MinShardBlocks: 1})
+	if err != nil {
+		t.Fatalf("NewLogFetcher: %v", err)
+	}
+
+	_, err = fetcher.fetchShard(context.Background(), ethereum.FilterQuery{}, 1, 100)
+	if err == nil {
+		t.Fatal("expected the non-range error to propagate, got nil")
+	}
+	if client.calls != 1 {
+		t.Errorf("got %d FilterLogs calls, want exactly 1 (a non-range error shouldn't trigger sharding)", client.calls)
+	}
+}
+
+// onceErrClient always fails FilterLogs with a fixed, non-"range too large" error, counting calls.
+type onceErrClient struct {
+	err error
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *onceErrClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	return nil, c.err
+}
+
+func (c *onceErrClient) TransactionByHash(ctx context.Context, txHash ethcommon.Hash) (*types.Transaction, bool, error) {
+	return nil, false, errors.New("not implemented")
+}
+
+func TestIsRangeTooLargeErr(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{"query returned more than 10000 results", true},
+		{"block range is too large", true},
+		{"limit exceeded for this request", true},
+		{"connection reset by peer", false},
+		{"execution reverted", false},
+	}
+	for _, tt := range tests {
+		if got := isRangeTooLargeErr(errors.New(tt.msg)); got != tt.want {
+			t.Errorf("isRangeTooLargeErr(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func msgNumTopic(msgNum *big.Int) ethcommon.Hash {
+	var topic ethcommon.Hash
+	copy(topic[:], math.U256Bytes(msgNum))
+	return topic
+}
+
+// messageLogsClient simulates an origin-message provider: for every hash in the requested query's
+// second topic, it returns one log (topic[0] echoing the caller's origin topic, TxHash derived from
+// the message number) and records the set of message-number topics it was actually asked for, so
+// tests can assert on what FetchMessageLogs narrowed its live query down to.
+type messageLogsClient struct {
+	originTopic ethcommon.Hash
+
+	mu            sync.Mutex
+	queriedTopics []ethcommon.Hash
+}
+
+func (c *messageLogsClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	c.mu.Lock()
+	c.queriedTopics = append(c.queriedTopics, query.Topics[1]...)
+	c.mu.Unlock()
+
+	logs := make([]types.Log, 0, len(query.Topics[1]))
+	for _, topic := range query.Topics[1] {
+		logs = append(logs, types.Log{
+			Topics: []ethcommon.Hash{c.originTopic, topic},
+			TxHash: ethcommon.BytesToHash(topic[:]),
+		})
+	}
+	return logs, nil
+}
+
+func (c *messageLogsClient) TransactionByHash(ctx context.Context, txHash ethcommon.Hash) (*types.Transaction, bool, error) {
+	return types.NewTx(&types.DynamicFeeTx{Gas: 21000}), false, nil
+}
+
+func TestFetchMessageLogsNarrowsLiveQueryToCacheMisses(t *testing.T) {
+	originTopic := ethcommon.HexToHash("0xaaaa")
+	client := &messageLogsClient{originTopic: originTopic}
+	fetcher, err := NewLogFetcher(client, LogFetcherConfig{
+		MaxConcurrentShards: 1,
+		MinShardBlocks:      1,
+		CachePath:           filepath.Join(t.TempDir(), "logcache.db"),
+		CacheCapacity:       100,
+	})
+	if err != nil {
+		t.Fatalf("NewLogFetcher: %v", err)
+	}
+	defer fetcher.Close()
+
+	address := testLogCacheAddr(1)
+	msgNums := []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}
+
+	// Pre-populate the cache with msgNum 1, as if a prior FetchMessageLogs call (or restart) had
+	// already resolved it, leaving 2 and 3 as the only message numbers that still need fetching.
+	cachedTx := types.NewTx(&types.DynamicFeeTx{Gas: 21000})
+	if err := fetcher.cache.put(address, originTopic, msgNums[0], types.Log{Topics: []ethcommon.Hash{originTopic, msgNumTopic(msgNums[0])}}, cachedTx); err != nil {
+		t.Fatalf("cache.put: %v", err)
+	}
+
+	query := ethereum.FilterQuery{
+		FromBlock: big.NewInt(1),
+		ToBlock:   big.NewInt(100),
+		Addresses: []ethcommon.Address{address},
+		Topics: [][]ethcommon.Hash{
+			{originTopic},
+			{msgNumTopic(msgNums[0]), msgNumTopic(msgNums[1]), msgNumTopic(msgNums[2])},
+		},
+	}
+
+	logs, txData, err := fetcher.FetchMessageLogs(context.Background(), query, msgNums, originTopic)
+	if err != nil {
+		t.Fatalf("FetchMessageLogs: %v", err)
+	}
+
+	if len(client.queriedTopics) != 2 {
+		t.Fatalf("live query asked for %d message-number topics, want 2 (the cache hit for msgNum 1 should have excluded it)", len(client.queriedTopics))
+	}
+	for _, cachedTopic := range []ethcommon.Hash{msgNumTopic(msgNums[0])} {
+		for _, queried := range client.queriedTopics {
+			if queried == cachedTopic {
+				t.Errorf("live query re-fetched already-cached msgNum topic %s", cachedTopic.Hex())
+			}
+		}
+	}
+
+	if len(logs) != 3 {
+		t.Fatalf("got %d logs, want 3 (one per message number, none duplicated)", len(logs))
+	}
+	if len(txData) != 3 {
+		t.Fatalf("got %d txData entries, want 3", len(txData))
+	}
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transactauth
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"github.com/pkg/errors"
+)
+
+// Authorizer signs EIP-7702 set-code authorizations - the {chainID, address, nonce, v, r, s}
+// tuples that populate a SetCodeTx's AuthList - independent of whatever key signs the wrapping
+// transaction itself, since the authority and the transaction's sender don't have to be the same
+// account.
+type Authorizer struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewAuthorizer builds an Authorizer that signs authorizations with key. Pass the same key used
+// for the wrapping transaction to have an account authorize its own delegation, or a separately
+// supplied key when the authority differs from the transaction's sender.
+func NewAuthorizer(key *ecdsa.PrivateKey) *Authorizer {
+	return &Authorizer{key: key}
+}
+
+// Authority returns the address this Authorizer signs authorizations on behalf of.
+func (a *Authorizer) Authority() ethcommon.Address {
+	return crypto.PubkeyToAddress(a.key.PublicKey)
+}
+
+// Authorize signs an EIP-7702 authorization delegating the authorizer's account to delegate's code,
+// valid on chainID at the given nonce. Per EIP-7702, when the authorizing account is also the
+// transaction's sender, nonce must equal the transaction's own nonce (the sender's nonce hasn't
+// been bumped yet at authorization-validation time); when it's a different account, nonce is that
+// account's current on-chain nonce.
+func (a *Authorizer) Authorize(chainID *big.Int, delegate ethcommon.Address, nonce uint64) (types.SetCodeAuthorization, error) {
+	unsigned := types.SetCodeAuthorization{
+		ChainID: *uint256.MustFromBig(chainID),
+		Address: delegate,
+		Nonce:   nonce,
+	}
+	signed, err := types.SignSetCode(a.key, unsigned)
+	if err != nil {
+		return types.SetCodeAuthorization{}, errors.WithStack(err)
+	}
+	return signed, nil
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transactauth
+
+import (
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestAuthorizerAuthorizeRecoversToAuthority(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	authorizer := NewAuthorizer(key)
+	delegate := ethcommon.HexToAddress("0x2222222222222222222222222222222222222222")
+	chainID := big.NewInt(42161)
+
+	signed, err := authorizer.Authorize(chainID, delegate, 3)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if signed.Address != delegate {
+		t.Errorf("Address = %s, want %s", signed.Address.Hex(), delegate.Hex())
+	}
+	if signed.Nonce != 3 {
+		t.Errorf("Nonce = %d, want 3", signed.Nonce)
+	}
+	authority, err := signed.Authority()
+	if err != nil {
+		t.Fatalf("Authority: %v", err)
+	}
+	if authority != authorizer.Authority() {
+		t.Errorf("recovered authority %s, want %s", authority.Hex(), authorizer.Authority().Hex())
+	}
+}
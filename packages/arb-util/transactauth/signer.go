@@ -0,0 +1,178 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transactauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/pkg/errors"
+)
+
+// TxSigner signs an already-built, unsigned transaction and returns the signed result. Unlike a
+// bind.TransactOpts.Signer func, a TxSigner doesn't have to hold a private key in process memory:
+// implementations may forward the raw transaction to clef, a cloud KMS, or any other remote signer.
+type TxSigner interface {
+	SignTx(ctx context.Context, tx *types.Transaction) (*types.Transaction, error)
+}
+
+// KeyedTxSigner adapts an in-memory bind.TransactOpts signer (the kind produced by
+// bind.NewKeyedTransactorWithChainID) to the TxSigner interface, so callers that already hold a
+// local key don't have to change anything to keep working.
+type KeyedTxSigner struct {
+	opts *bind.TransactOpts
+}
+
+func NewKeyedTxSigner(opts *bind.TransactOpts) *KeyedTxSigner {
+	return &KeyedTxSigner{opts: opts}
+}
+
+func (s *KeyedTxSigner) SignTx(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	signed, err := s.opts.Signer(s.opts.From, tx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return signed, nil
+}
+
+// ClefTxSigner signs transactions with a remote signer speaking clef's account_signTransaction
+// JSON-RPC API, keeping the private key off the host running the sequencer entirely.
+type ClefTxSigner struct {
+	client *rpc.Client
+	from   ethcommon.Address
+}
+
+// DialClefTxSigner connects to a clef (or clef-compatible) external signer over JSON-RPC.
+func DialClefTxSigner(ctx context.Context, endpoint string, from ethcommon.Address) (*ClefTxSigner, error) {
+	client, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &ClefTxSigner{client: client, from: from}, nil
+}
+
+// clefTransactionArgs mirrors the SendTxArgs shape clef's account_signTransaction expects.
+type clefTransactionArgs struct {
+	From                 ethcommon.Address  `json:"from"`
+	To                   *ethcommon.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64     `json:"gas"`
+	GasPrice             *hexutil.Big       `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big       `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big       `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big       `json:"value"`
+	Nonce                hexutil.Uint64     `json:"nonce"`
+	Data                 hexutil.Bytes      `json:"data"`
+	ChainID              *hexutil.Big       `json:"chainId,omitempty"`
+}
+
+func (s *ClefTxSigner) SignTx(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	args := clefTransactionArgs{
+		From:                 s.from,
+		To:                   tx.To(),
+		Gas:                  hexutil.Uint64(tx.Gas()),
+		MaxFeePerGas:         (*hexutil.Big)(tx.GasFeeCap()),
+		MaxPriorityFeePerGas: (*hexutil.Big)(tx.GasTipCap()),
+		Value:                (*hexutil.Big)(tx.Value()),
+		Nonce:                hexutil.Uint64(tx.Nonce()),
+		Data:                 tx.Data(),
+		ChainID:              (*hexutil.Big)(tx.ChainId()),
+	}
+	var result struct {
+		Raw hexutil.Bytes      `json:"raw"`
+		Tx  *types.Transaction `json:"tx"`
+	}
+	if err := s.client.CallContext(ctx, &result, "account_signTransaction", &args); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(result.Raw); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return signed, nil
+}
+
+// DigestSigner signs a 32-byte digest and returns the (r, s) pair of an ECDSA secp256k1 signature
+// over it, without revealing the recovery id. This is the shape both AWS KMS and GCP KMS expose
+// for secp256k1 keys: they'll sign a digest, but neither returns a recoverable (v, r, s) triple.
+type DigestSigner func(ctx context.Context, digest [32]byte) (r, s *big.Int, err error)
+
+// KMSTxSigner signs transactions by hashing them with the given chain's EIP-155/1559/4844 signer
+// and handing the digest to a remote HSM-backed key (AWS KMS, GCP KMS, ...), then reconstructing
+// the recovery id locally since neither service provides one.
+type KMSTxSigner struct {
+	pubKey  *ecdsa.PublicKey
+	chainID *big.Int
+	sign    DigestSigner
+}
+
+func NewKMSTxSigner(pubKey *ecdsa.PublicKey, chainID *big.Int, sign DigestSigner) *KMSTxSigner {
+	return &KMSTxSigner{pubKey: pubKey, chainID: chainID, sign: sign}
+}
+
+func (s *KMSTxSigner) SignTx(ctx context.Context, tx *types.Transaction) (*types.Transaction, error) {
+	signer := types.LatestSignerForChainID(s.chainID)
+	digest := signer.Hash(tx)
+	r, sVal, err := s.sign(ctx, digest)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sig, err := canonicalSignature(s.pubKey, digest, r, sVal)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	signed, err := tx.WithSignature(signer, sig)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return signed, nil
+}
+
+// secp256k1HalfOrder is half of the secp256k1 curve order; EIP-2 requires the S value of a
+// signature to be at most this, so a KMS that returns the "other" canonical S must be flipped.
+var secp256k1HalfOrder = new(big.Int).Rsh(crypto.S256().Params().N, 1)
+
+// canonicalSignature turns a KMS-produced (r, s) pair into the 65-byte [R || S || V] signature
+// go-ethereum expects: it forces S into its low-S form per EIP-2, then recovers the correct
+// recovery id by checking both candidates against the known public key.
+func canonicalSignature(pubKey *ecdsa.PublicKey, digest [32]byte, r, s *big.Int) ([]byte, error) {
+	if s.Cmp(secp256k1HalfOrder) > 0 {
+		s = new(big.Int).Sub(crypto.S256().Params().N, s)
+	}
+	sig := make([]byte, 65)
+	copy(sig[32-len(r.Bytes()):32], r.Bytes())
+	copy(sig[64-len(s.Bytes()):64], s.Bytes())
+
+	wantPubKeyBytes := crypto.FromECDSAPub(pubKey)
+	for recoveryID := byte(0); recoveryID < 2; recoveryID++ {
+		sig[64] = recoveryID
+		recoveredPubKeyBytes, err := crypto.Ecrecover(digest[:], sig)
+		if err != nil {
+			continue
+		}
+		if ethcommon.Bytes2Hex(recoveredPubKeyBytes) == ethcommon.Bytes2Hex(wantPubKeyBytes) {
+			return sig, nil
+		}
+	}
+	return nil, errors.New("KMS signature did not recover to the expected public key under either recovery id")
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2024, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transactauth
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// splitSignature pulls the (r, s) pair out of a 65-byte [R || S || V] go-ethereum signature.
+func splitSignature(sig []byte) (r, s *big.Int) {
+	return new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+}
+
+func TestCanonicalSignatureAcceptsLowS(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	var digest [32]byte
+	copy(digest[:], crypto.Keccak256([]byte("low-s test message")))
+
+	sig, err := crypto.Sign(digest[:], key)
+	if err != nil {
+		t.Fatalf("crypto.Sign: %v", err)
+	}
+	r, s := splitSignature(sig)
+	if s.Cmp(secp256k1HalfOrder) > 0 {
+		t.Fatalf("test setup expected crypto.Sign to already return a low-S signature")
+	}
+
+	got, err := canonicalSignature(&key.PublicKey, digest, r, s)
+	if err != nil {
+		t.Fatalf("canonicalSignature: %v", err)
+	}
+	gotR, gotS := splitSignature(got)
+	if gotR.Cmp(r) != 0 || gotS.Cmp(s) != 0 {
+		t.Errorf("canonicalSignature changed an already-canonical (r, s): got (%s, %s), want (%s, %s)", gotR, gotS, r, s)
+	}
+	if got[64] != sig[64] {
+		t.Errorf("recovery id = %d, want %d to match crypto.Sign's own", got[64], sig[64])
+	}
+}
+
+func TestCanonicalSignatureFlipsHighS(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	var digest [32]byte
+	copy(digest[:], crypto.Keccak256([]byte("high-s test message")))
+
+	sig, err := crypto.Sign(digest[:], key)
+	if err != nil {
+		t.Fatalf("crypto.Sign: %v", err)
+	}
+	r, s := splitSignature(sig)
+	// Simulate a KMS that returned the "other" canonical S, the one EIP-2 forbids.
+	highS := new(big.Int).Sub(crypto.S256().Params().N, s)
+
+	got, err := canonicalSignature(&key.PublicKey, digest, r, highS)
+	if err != nil {
+		t.Fatalf("canonicalSignature: %v", err)
+	}
+	_, gotS := splitSignature(got)
+	if gotS.Cmp(secp256k1HalfOrder) > 0 {
+		t.Errorf("canonicalSignature returned a high-S value: %s", gotS)
+	}
+	if gotS.Cmp(s) != 0 {
+		t.Errorf("flipped S = %s, want the original low-S value %s", gotS, s)
+	}
+	recovered, err := crypto.Ecrecover(digest[:], got)
+	if err != nil {
+		t.Fatalf("Ecrecover: %v", err)
+	}
+	if !bytes.Equal(recovered, crypto.FromECDSAPub(&key.PublicKey)) {
+		t.Error("canonicalSignature's output does not recover to the expected public key")
+	}
+}
+
+func TestCanonicalSignatureNeitherRecoveryIDMatches(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	otherKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	var digest [32]byte
+	copy(digest[:], crypto.Keccak256([]byte("mismatched key test message")))
+
+	sig, err := crypto.Sign(digest[:], key)
+	if err != nil {
+		t.Fatalf("crypto.Sign: %v", err)
+	}
+	r, s := splitSignature(sig)
+
+	// The (r, s) pair was produced by key, but we ask canonicalSignature to recover it against a
+	// different public key, which should fail under both candidate recovery ids.
+	if _, err := canonicalSignature(&otherKey.PublicKey, digest, r, s); err == nil {
+		t.Fatal("expected an error when the signature can't recover to the given public key, got nil")
+	}
+}
+
+func TestKMSTxSignerRoundTrip(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	chainID := big.NewInt(42161)
+	signTx := NewKMSTxSigner(&key.PublicKey, chainID, func(ctx context.Context, digest [32]byte) (*big.Int, *big.Int, error) {
+		sig, err := crypto.Sign(digest[:], key)
+		if err != nil {
+			return nil, nil, err
+		}
+		r, s := splitSignature(sig)
+		return r, s, nil
+	})
+
+	unsigned := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     0,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(2e9),
+		Gas:       21000,
+		Value:     big.NewInt(0),
+	})
+	signed, err := signTx.SignTx(context.Background(), unsigned)
+	if err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+
+	signer := types.LatestSignerForChainID(chainID)
+	from, err := types.Sender(signer, signed)
+	if err != nil {
+		t.Fatalf("recovering sender: %v", err)
+	}
+	want := crypto.PubkeyToAddress(key.PublicKey)
+	if from != want {
+		t.Errorf("recovered sender %s, want %s", from.Hex(), want.Hex())
+	}
+}